@@ -2,23 +2,34 @@
 // Use of this source code is governed by the ISC
 // license that can be found in the LICENSE.md file.
 
-// Package cli provides utilities for building simple command-line applications
-// that have no subcommands.
+// Package cli provides utilities for building simple command-line applications.
+//
+// By default an application is a single command. Applications that implement
+// [HasCommands] dispatch to named subcommands instead.
 package cli
 
 import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"os/signal"
 	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"go.astrophena.name/base/logger"
 	"go.astrophena.name/base/syncx"
@@ -26,10 +37,24 @@ import (
 )
 
 // Main is a helper function that handles common startup tasks for command-line
-// applications. It sets up signal handling for interrupts, runs the application,
-// and prints errors to stderr.
+// applications. It sets up signal handling for interrupts and termination
+// requests, runs the application, and prints errors to stderr.
+//
+// The received signal, if any, can be obtained by [SignalFromContext].
+//
+// Main exits with a status code determined by the returned error:
+//
+//   - 0 if no error occurred or the application exited after showing help,
+//     version or completion script;
+//   - the code returned by ExitCode, if the error implements [ExitCoder];
+//   - 128 plus the signal number (130 for interrupt), if the application was
+//     stopped by a signal;
+//   - 2 if the error wraps [ErrInvalidArgs], including invalid flags;
+//   - 1 otherwise.
 func Main(app App) {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := notifyContext(context.Background(), sigc, func() { signal.Stop(sigc) })
 	defer cancel()
 
 	err := Run(ctx, app)
@@ -41,7 +66,73 @@ func Main(app App) {
 	if isPrintableError(err) {
 		fmt.Fprintln(os.Stderr, err)
 	}
-	os.Exit(1)
+	os.Exit(ExitCode(ctx, err))
+}
+
+// ExitCoder is implemented by errors that determine the exit code of the
+// application.
+type ExitCoder interface {
+	error
+
+	// ExitCode returns the exit code.
+	ExitCode() int
+}
+
+// ExitCode returns the exit code that [Main] exits with when [Run] returns err.
+// ctx is used to determine whether the application was stopped by a signal.
+func ExitCode(ctx context.Context, err error) int {
+	if err == nil {
+		return 0
+	}
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+	if errors.Is(err, flag.ErrHelp) || errors.Is(err, ErrExitVersion) || errors.Is(err, ErrExitCompletion) {
+		return 0
+	}
+	if sig, ok := SignalFromContext(ctx).(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	if errors.Is(err, ErrInvalidArgs) {
+		return 2
+	}
+	return 1
+}
+
+// notifyContext returns a copy of the parent context that is canceled when
+// a signal is received on c, recording it as a cause of cancellation. Calling
+// the returned cancel function calls stop and cancels the context.
+func notifyContext(parent context.Context, c <-chan os.Signal, stop func()) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	go func() {
+		select {
+		case sig := <-c:
+			cancel(&signalError{sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+type signalError struct{ sig os.Signal }
+
+func (e *signalError) Error() string { return "received signal " + e.sig.String() }
+
+// SignalFromContext returns the signal that caused ctx to be canceled, or nil
+// if ctx wasn't canceled by a signal.
+//
+// This allows applications to distinguish between being interrupted and
+// finishing normally, for example to log "shutting down due to terminated".
+func SignalFromContext(ctx context.Context) os.Signal {
+	var se *signalError
+	if errors.As(context.Cause(ctx), &se) {
+		return se.sig
+	}
+	return nil
 }
 
 type unprintableError struct{ err error }
@@ -86,6 +177,77 @@ type HasFlags interface {
 	Flags(*flag.FlagSet)
 }
 
+// HasCommands represents a command-line application that dispatches to named
+// subcommands.
+//
+// When Commands returns a non-empty map, [Run] consumes the first non-flag
+// argument, looks up the matching subcommand and runs it with the remaining
+// arguments. Subcommands can have their own flags and subcommands. The Run
+// method of an application that has subcommands is not called.
+type HasCommands interface {
+	App
+
+	// Commands returns subcommands keyed by their names.
+	Commands() map[string]App
+}
+
+// HasConfig represents a command-line application that can read flag values
+// from a configuration file, passed with the -config flag.
+//
+// The configuration file consists of key=value lines, where key is a flag name.
+// Blank lines and lines starting with # are ignored. Flags explicitly set on
+// the command line take precedence over values from the configuration file.
+// Unknown keys result in an error wrapping [ErrInvalidArgs].
+type HasConfig interface {
+	HasFlags
+
+	// DefaultConfig returns the path to the configuration file that is read
+	// when the -config flag isn't set. It may return an empty string, meaning
+	// that no configuration file is read by default. Unlike a file passed with
+	// -config, the default file may not exist.
+	DefaultConfig() string
+}
+
+// Closer represents a command-line application that holds resources that
+// must be released after it has run.
+//
+// Close is called after Run returns, regardless of whether it returned an
+// error. The context passed to Close is not canceled when the context passed to
+// Run is, but has a short timeout. Errors returned by Run and Close are joined
+// with [errors.Join].
+type Closer interface {
+	App
+
+	// Close releases resources held by the application.
+	Close(context.Context) error
+}
+
+// ArgsExpander represents a command-line application whose arguments can be
+// read from standard input or files.
+//
+// If ExpandArgs returns true, [Run] replaces a lone "-" argument with
+// newline-separated arguments read from standard input, and an "@file"
+// argument with newline-separated arguments read from file, before parsing
+// flags. Blank lines are skipped. Arguments after "--" are left as is.
+//
+// This is opt-in, because many applications use "-" to mean standard input as
+// data.
+type ArgsExpander interface {
+	App
+
+	// ExpandArgs reports whether arguments should be expanded.
+	ExpandArgs() bool
+}
+
+// HasDescription represents a command-line application that has a one-line
+// description, which is shown in the list of available subcommands.
+type HasDescription interface {
+	App
+
+	// Description returns a one-line description of the application.
+	Description() string
+}
+
 // AppFunc is a function type that implements the [App] interface.
 // AppFunc doesn't have it's own flags.
 type AppFunc func(context.Context) error
@@ -124,13 +286,19 @@ type Env struct {
 	Stdout io.Writer
 	Stderr io.Writer
 
+	// LogPrefix and LogFlags are passed to log.New when constructing the
+	// logger used by Logf. They are read once, on the first call to Logf.
+	// By default, messages are written without prefix and timestamps.
+	LogPrefix string
+	LogFlags  int
+
 	logf syncx.Lazy[logger.Logf]
 }
 
 // Logf writes the formatted message to standard error of this environment.
 func (e *Env) Logf(format string, args ...any) {
 	e.logf.Get(func() logger.Logf {
-		return log.New(e.Stderr, "", 0).Printf
+		return log.New(e.Stderr, e.LogPrefix, e.LogFlags).Printf
 	})(format, args...)
 }
 
@@ -146,30 +314,55 @@ func OSEnv() *Env {
 }
 
 // Run handles the command-line application startup.
+//
+// Besides the application's own flags, Run handles -version, which prints
+// version information and makes Run return [ErrExitVersion]. With
+// -version=json, the [version.Info] is printed to stdout as JSON, with name,
+// version, commit, built_at, dirty, go, os and arch fields.
 func Run(ctx context.Context, app App) error {
 	name := version.CmdName()
+	env := GetEnv(ctx)
 
-	flags := flag.NewFlagSet(name, flag.ContinueOnError)
-	if fa, ok := app.(HasFlags); ok {
-		fa.Flags(flags)
-	}
-
+	flags := newFlagSet(name, app, env, true)
 	var (
 		cpuProfile = flags.String("cpuprofile", "", "Write CPU profile to `file`.")
 		memProfile = flags.String("memprofile", "", "Write memory profile to `file`.")
+		traceFile  = new(string)
 	)
-	var showVersion bool
-	if flags.Lookup("version") == nil {
-		flags.BoolVar(&showVersion, "version", false, "Show version.")
+	if flags.Lookup("trace") == nil {
+		flags.StringVar(traceFile, "trace", "", "Write execution trace to `file`.")
+	}
+	showVersion := versionFlag(flags)
+	completion := completionFlag(flags)
+	var configFile *string
+	if ca, ok := app.(HasConfig); ok && flags.Lookup("config") == nil {
+		configFile = flags.String("config", ca.DefaultConfig(), "Read flag values from `file`.")
 	}
 
-	env := GetEnv(ctx)
+	if ae, ok := app.(ArgsExpander); ok && ae.ExpandArgs() {
+		args, err := expandArgs(env)
+		if err != nil {
+			return err
+		}
+		env.Args = args
+	}
 
-	flags.Usage = usage(flags, env.Stderr)
-	flags.SetOutput(env.Stderr)
 	if err := flags.Parse(env.Args); err != nil {
-		// Already printed to stderr by flag package, so mark as an unprintable error.
-		return &unprintableError{err}
+		return parseError(err)
+	}
+	if configFile != nil && *configFile != "" {
+		if err := loadConfig(flags, *configFile); err != nil {
+			// The default configuration file is optional.
+			if !errors.Is(err, fs.ErrNotExist) || isFlagSet(flags, "config") {
+				return err
+			}
+		}
+	}
+	if *completion != "" {
+		if err := writeCompletion(env.Stdout, string(*completion), name, flags); err != nil {
+			return err
+		}
+		return ErrExitCompletion
 	}
 	if *cpuProfile != "" {
 		f, err := os.Create(*cpuProfile)
@@ -182,15 +375,25 @@ func Run(ctx context.Context, app App) error {
 		}
 		defer pprof.StopCPUProfile()
 	}
+	if *traceFile != "" {
+		f, err := os.Create(*traceFile)
+		if err != nil {
+			return fmt.Errorf("could not create trace: %w", err)
+		}
+		defer f.Close()
+		if err := trace.Start(f); err != nil {
+			return fmt.Errorf("could not start trace: %w", err)
+		}
+		defer trace.Stop()
+	}
 
-	if showVersion {
-		fmt.Fprint(env.Stderr, version.Version())
-		return ErrExitVersion
+	if *showVersion != "" {
+		return printVersion(env, *showVersion)
 	}
 
 	env.Args = flags.Args()
 
-	if err := app.Run(WithEnv(ctx, env)); err != nil {
+	if err := dispatch(ctx, env, name, app); err != nil {
 		return err
 	}
 
@@ -209,13 +412,256 @@ func Run(ctx context.Context, app App) error {
 	return nil
 }
 
-func usage(flags *flag.FlagSet, stderr io.Writer) func() {
+// loadConfig applies flag values from the configuration file to flags that
+// weren't explicitly set.
+func loadConfig(flags *flag.FlagSet, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config: %w", err)
+	}
+
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%w: %s:%d: want key=value, got %q", ErrInvalidArgs, path, lineno, line)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if key == "config" || flags.Lookup(key) == nil {
+			return fmt.Errorf("%w: %s:%d: unknown flag %q", ErrInvalidArgs, path, lineno, key)
+		}
+		if set[key] {
+			continue
+		}
+		if err := flags.Set(key, val); err != nil {
+			return fmt.Errorf("%w: %s:%d: invalid value %q for flag %q: %v", ErrInvalidArgs, path, lineno, val, key, err)
+		}
+	}
+	return s.Err()
+}
+
+// isFlagSet reports whether the flag named name was set on the command line.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	var set bool
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// expandArgs returns env.Args with "-" and "@file" arguments replaced by
+// arguments read from env.Stdin and file. See [ArgsExpander].
+func expandArgs(env *Env) ([]string, error) {
+	var args []string
+	for i, arg := range env.Args {
+		var (
+			r   io.Reader
+			src string
+		)
+		switch {
+		case arg == "--":
+			return append(args, env.Args[i:]...), nil
+		case arg == "-":
+			if env.Stdin == nil {
+				return nil, fmt.Errorf("%w: no standard input to read arguments from", ErrInvalidArgs)
+			}
+			r, src = env.Stdin, "standard input"
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			src = arg[1:]
+			f, err := os.Open(src)
+			if err != nil {
+				return nil, fmt.Errorf("%w: could not read arguments: %w", ErrInvalidArgs, err)
+			}
+			defer f.Close()
+			r = f
+		default:
+			args = append(args, arg)
+			continue
+		}
+
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			if line := strings.TrimSpace(s.Text()); line != "" {
+				args = append(args, line)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("could not read arguments from %s: %w", src, err)
+		}
+	}
+	return args, nil
+}
+
+// dispatch runs app, or if it has subcommands, the subcommand named by the
+// first argument in env.Args.
+func dispatch(ctx context.Context, env *Env, name string, app App) error {
+	cmds := commands(app)
+	if len(cmds) == 0 {
+		return runApp(WithEnv(ctx, env), app)
+	}
+
+	if len(env.Args) == 0 {
+		printCommands(env.Stderr, cmds)
+		return fmt.Errorf("%w: missing command", ErrInvalidArgs)
+	}
+	cmdName := env.Args[0]
+	cmd, ok := cmds[cmdName]
+	if !ok {
+		printCommands(env.Stderr, cmds)
+		return fmt.Errorf("%w: unknown command %q", ErrInvalidArgs, cmdName)
+	}
+
+	name += " " + cmdName
+	flags := newFlagSet(name, cmd, env, false)
+	showVersion := versionFlag(flags)
+	if err := flags.Parse(env.Args[1:]); err != nil {
+		return parseError(err)
+	}
+	if *showVersion != "" {
+		return printVersion(env, *showVersion)
+	}
+	env.Args = flags.Args()
+
+	return dispatch(ctx, env, name, cmd)
+}
+
+// parseError wraps an error returned by flag parsing.
+func parseError(err error) error {
+	// Already printed to stderr by flag package, so mark as an unprintable error.
+	if errors.Is(err, flag.ErrHelp) {
+		return &unprintableError{err}
+	}
+	return &unprintableError{fmt.Errorf("%w: %w", ErrInvalidArgs, err)}
+}
+
+// closeTimeout is the time given to Close of applications implementing
+// [Closer].
+const closeTimeout = 5 * time.Second
+
+// runApp runs app and, if it implements [Closer], closes it.
+func runApp(ctx context.Context, app App) error {
+	err := app.Run(ctx)
+	if c, ok := app.(Closer); ok {
+		// Close even if ctx was canceled, keeping its values.
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+		defer cancel()
+		if cerr := c.Close(closeCtx); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}
+	return err
+}
+
+// newFlagSet returns a new flag set for app, populated with its flags, if any.
+func newFlagSet(name string, app App, env *Env, topLevel bool) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if fa, ok := app.(HasFlags); ok {
+		fa.Flags(flags)
+	}
+	flags.Usage = usage(flags, commands(app), env.Stderr, topLevel)
+	flags.SetOutput(env.Stderr)
+	return flags
+}
+
+// versionFlag registers the -version flag, unless the application already
+// defined it.
+func versionFlag(flags *flag.FlagSet) *versionValue {
+	showVersion := new(versionValue)
+	if flags.Lookup("version") == nil {
+		flags.Var(showVersion, "version", "Show version. Use -version=json for machine-readable output.")
+	}
+	return showVersion
+}
+
+// versionValue is the value of the -version flag. It's a boolean flag that
+// also accepts "json".
+type versionValue string
+
+const (
+	versionText = "text"
+	versionJSON = "json"
+)
+
+func (v *versionValue) IsBoolFlag() bool { return true }
+func (v *versionValue) String() string   { return string(*v) }
+
+func (v *versionValue) Set(s string) error {
+	if s == versionJSON {
+		*v = versionJSON
+		return nil
+	}
+	show, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("want boolean or %q", versionJSON)
+	}
+	*v = ""
+	if show {
+		*v = versionText
+	}
+	return nil
+}
+
+// printVersion prints version information in the requested format: as a human
+// readable text to stderr, or as JSON to stdout. See [version.Info] for the
+// fields.
+func printVersion(env *Env, format versionValue) error {
+	if format == versionJSON {
+		b, err := json.MarshalIndent(version.Version(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Stdout, "%s\n", b)
+		return ErrExitVersion
+	}
+	fmt.Fprint(env.Stderr, version.Version())
+	return ErrExitVersion
+}
+
+func commands(app App) map[string]App {
+	if ca, ok := app.(HasCommands); ok {
+		return ca.Commands()
+	}
+	return nil
+}
+
+func printCommands(w io.Writer, cmds map[string]App) {
+	fmt.Fprint(w, "Available commands:\n\n")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		var desc string
+		if da, ok := cmds[name].(HasDescription); ok {
+			desc = da.Description()
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, desc)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+func usage(flags *flag.FlagSet, cmds map[string]App, stderr io.Writer, topLevel bool) func() {
 	return func() {
-		if docSrc != nil {
+		if topLevel && docSrc != nil {
 			fmt.Fprintf(stderr, "%s\n", doc.Get(parseDocComment))
 		}
+		if len(cmds) > 0 {
+			printCommands(stderr, cmds)
+		}
 		fmt.Fprint(stderr, "Available flags:\n\n")
-		flags.PrintDefaults()
+		printDefaults(flags)
 	}
 }
 
@@ -0,0 +1,491 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.astrophena.name/base/cli"
+	"go.astrophena.name/base/cli/clitest"
+	"go.astrophena.name/base/testutil"
+)
+
+type greetApp struct {
+	name string
+}
+
+func (a *greetApp) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&a.name, "name", "world", "Who to greet.")
+}
+
+func (a *greetApp) Description() string { return "Print a greeting." }
+
+func (a *greetApp) Run(ctx context.Context) error {
+	env := cli.GetEnv(ctx)
+	fmt.Fprintf(env.Stdout, "hello, %s %v\n", a.name, env.Args)
+	return nil
+}
+
+type muxApp struct {
+	greet *greetApp
+}
+
+func (a *muxApp) Commands() map[string]cli.App {
+	return map[string]cli.App{
+		"greet": a.greet,
+		"noop":  cli.AppFunc(func(context.Context) error { return nil }),
+	}
+}
+
+func (a *muxApp) Run(context.Context) error { panic("must not be called") }
+
+func TestCommands(t *testing.T) {
+	clitest.Run(t, func(t *testing.T) *muxApp {
+		return &muxApp{greet: new(greetApp)}
+	}, map[string]clitest.Case[*muxApp]{
+		"runs subcommand": {
+			Args:         []string{"greet", "a", "b"},
+			WantInStdout: "hello, world [a b]",
+		},
+		"subcommand flags": {
+			Args:         []string{"greet", "-name", "gopher"},
+			WantInStdout: "hello, gopher []",
+		},
+		"regexp": {
+			Args:             []string{"greet", "-name", "gopher", "42"},
+			WantInStdout:     "gopher",
+			WantStdoutRegexp: `^hello, \w+ \[\d+\]\n$`,
+			WantStderrRegexp: `^$`,
+		},
+		"unknown command": {
+			Args:         []string{"frobnicate"},
+			WantErr:      cli.ErrInvalidArgs,
+			WantInStderr: "greet  Print a greeting.",
+		},
+		"missing command": {
+			Args:         []string{},
+			WantErr:      cli.ErrInvalidArgs,
+			WantInStderr: "Available commands:",
+		},
+		"help lists commands": {
+			Args:         []string{"-help"},
+			WantErr:      flag.ErrHelp,
+			WantInStderr: "greet  Print a greeting.",
+		},
+		"subcommand version": {
+			Args:         []string{"greet", "-version"},
+			WantErr:      cli.ErrExitVersion,
+			WantExitCode: ptr(0),
+		},
+		"golden stdout": {
+			Args:             []string{"greet", "-name", "golden", "x"},
+			WantStdoutGolden: "testdata/greet.golden",
+			WantExitCode:     ptr(0),
+		},
+		"invalid args exit code": {
+			Args:         []string{"frobnicate"},
+			WantExitCode: ptr(2),
+		},
+		"invalid flag exit code": {
+			Args:         []string{"greet", "-frobnicate"},
+			WantErr:      cli.ErrInvalidArgs,
+			WantExitCode: ptr(2),
+		},
+	})
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestVersion(t *testing.T) {
+	clitest.Run(t, func(t *testing.T) *configApp {
+		return new(configApp)
+	}, map[string]clitest.Case[*configApp]{
+		"plain": {
+			Args:         []string{"-version"},
+			WantErr:      cli.ErrExitVersion,
+			WantInStderr: "cli.test",
+			WantExitCode: ptr(0),
+		},
+		"json": {
+			Args:             []string{"-version=json"},
+			WantErr:          cli.ErrExitVersion,
+			WantInStdout:     `"name": "cli.test"`,
+			WantStderrRegexp: `^$`,
+			WantExitCode:     ptr(0),
+		},
+		"false": {
+			Args:         []string{"-version=false"},
+			WantInStdout: "name=world",
+		},
+		"invalid": {
+			Args:    []string{"-version=yaml"},
+			WantErr: cli.ErrInvalidArgs,
+		},
+	})
+}
+
+type configApp struct {
+	greetApp
+	verbose       bool
+	defaultConfig string
+}
+
+func (a *configApp) Flags(fs *flag.FlagSet) {
+	a.greetApp.Flags(fs)
+	fs.BoolVar(&a.verbose, "verbose", false, "Be verbose.")
+}
+
+func (a *configApp) DefaultConfig() string { return a.defaultConfig }
+
+func (a *configApp) Run(ctx context.Context) error {
+	fmt.Fprintf(cli.GetEnv(ctx).Stdout, "name=%s verbose=%v\n", a.name, a.verbose)
+	return nil
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var (
+		valid   = write("valid.conf", "# Comment.\n\nname = gopher\nverbose=true\n")
+		unknown = write("unknown.conf", "name=gopher\nfrobnicate=yes\n")
+		invalid = write("invalid.conf", "verbose=maybe\n")
+	)
+
+	clitest.Run(t, func(t *testing.T) *configApp {
+		return new(configApp)
+	}, map[string]clitest.Case[*configApp]{
+		"no config": {
+			Args:         []string{},
+			WantInStdout: "name=world verbose=false",
+		},
+		"config": {
+			Args:         []string{"-config", valid},
+			WantInStdout: "name=gopher verbose=true",
+		},
+		"command line wins": {
+			Args:         []string{"-config", valid, "-name", "override"},
+			WantInStdout: "name=override verbose=true",
+		},
+		"unknown key": {
+			Args:    []string{"-config", unknown},
+			WantErr: cli.ErrInvalidArgs,
+		},
+		"invalid value": {
+			Args:    []string{"-config", invalid},
+			WantErr: cli.ErrInvalidArgs,
+		},
+		"missing config": {
+			Args:    []string{"-config", filepath.Join(dir, "missing.conf")},
+			WantErr: fs.ErrNotExist,
+		},
+	})
+
+	clitest.Run(t, func(t *testing.T) *configApp {
+		return &configApp{defaultConfig: filepath.Join(dir, "missing.conf")}
+	}, map[string]clitest.Case[*configApp]{
+		"missing default config": {
+			Args:         []string{},
+			WantInStdout: "name=world verbose=false",
+		},
+		"missing default config set explicitly": {
+			Args:    []string{"-config", filepath.Join(dir, "missing.conf")},
+			WantErr: fs.ErrNotExist,
+		},
+	})
+
+	clitest.Run(t, func(t *testing.T) *configApp {
+		return &configApp{defaultConfig: valid}
+	}, map[string]clitest.Case[*configApp]{
+		"default config": {
+			Args:         []string{},
+			WantInStdout: "name=gopher verbose=true",
+		},
+	})
+}
+
+func TestProfiling(t *testing.T) {
+	dir := t.TempDir()
+	noop := cli.AppFunc(func(context.Context) error { return nil })
+
+	for _, name := range []string{"cpuprofile", "memprofile", "trace"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			env := &cli.Env{
+				Args:   []string{"-" + name, path},
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			}
+			if err := cli.Run(cli.WithEnv(context.Background(), env), noop); err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() == 0 {
+				t.Fatalf("%s is empty", path)
+			}
+		})
+	}
+}
+
+type expandApp struct {
+	greetApp
+}
+
+func (a *expandApp) ExpandArgs() bool { return true }
+
+func TestExpandArgs(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	if err := os.WriteFile(argsFile, []byte("x\n\ny\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	clitest.Run(t, func(t *testing.T) *expandApp {
+		return new(expandApp)
+	}, map[string]clitest.Case[*expandApp]{
+		"stdin": {
+			Args:         []string{"-"},
+			Stdin:        strings.NewReader("a\nb\nc\n"),
+			WantInStdout: "hello, world [a b c]",
+		},
+		"stdin flags": {
+			Args:         []string{"-", "d"},
+			Stdin:        strings.NewReader("-name\ngopher\nc\n"),
+			WantInStdout: "hello, gopher [c d]",
+		},
+		"file": {
+			Args:         []string{"@" + argsFile, "z"},
+			WantInStdout: "hello, world [x y z]",
+		},
+		"missing file": {
+			Args:    []string{"@" + argsFile + ".missing"},
+			WantErr: cli.ErrInvalidArgs,
+		},
+		"after terminator": {
+			Args:         []string{"--", "-", "@" + argsFile},
+			WantInStdout: "hello, world [- @" + argsFile + "]",
+		},
+	})
+
+	clitest.Run(t, func(t *testing.T) *greetApp {
+		return new(greetApp)
+	}, map[string]clitest.Case[*greetApp]{
+		"not opted in": {
+			Args:         []string{"-"},
+			Stdin:        strings.NewReader("a\nb\nc\n"),
+			WantInStdout: "hello, world [-]",
+		},
+	})
+}
+
+type closerApp struct {
+	runErr, closeErr error
+	closed           bool
+	closeCtxErr      error
+}
+
+func (a *closerApp) Run(context.Context) error { return a.runErr }
+
+func (a *closerApp) Close(ctx context.Context) error {
+	a.closed = true
+	a.closeCtxErr = ctx.Err()
+	return a.closeErr
+}
+
+func TestCloser(t *testing.T) {
+	var (
+		errRun   = errors.New("run failed")
+		errClose = errors.New("close failed")
+	)
+
+	cases := map[string]struct {
+		app      *closerApp
+		canceled bool
+		wantErrs []error
+	}{
+		"success": {
+			app: &closerApp{},
+		},
+		"run fails": {
+			app:      &closerApp{runErr: errRun},
+			wantErrs: []error{errRun},
+		},
+		"close fails": {
+			app:      &closerApp{closeErr: errClose},
+			wantErrs: []error{errClose},
+		},
+		"run and close fail": {
+			app:      &closerApp{runErr: errRun, closeErr: errClose},
+			wantErrs: []error{errRun, errClose},
+		},
+		"canceled context": {
+			app:      &closerApp{},
+			canceled: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+			env := &cli.Env{Stdout: io.Discard, Stderr: io.Discard}
+
+			err := cli.Run(cli.WithEnv(ctx, env), tc.app)
+
+			if !tc.app.closed {
+				t.Fatal("Close was not called")
+			}
+			if tc.app.closeCtxErr != nil {
+				t.Fatalf("context passed to Close is done: %v", tc.app.closeCtxErr)
+			}
+			if len(tc.wantErrs) == 0 && err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			for _, want := range tc.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("want %v in error, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func TestCompletion(t *testing.T) {
+	testutil.RunGolden(t, "testdata/completion/*.txt", func(t *testing.T, match string) []byte {
+		b, err := os.ReadFile(match)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stdout bytes.Buffer
+		env := &cli.Env{
+			Args:   []string{"-completion", strings.TrimSpace(string(b))},
+			Stdout: &stdout,
+			Stderr: io.Discard,
+		}
+		err = cli.Run(cli.WithEnv(context.Background(), env), new(configApp))
+		if !errors.Is(err, cli.ErrExitCompletion) {
+			t.Fatalf("want ErrExitCompletion, got %v", err)
+		}
+		return stdout.Bytes()
+	}, *update)
+}
+
+func TestCompletionHidden(t *testing.T) {
+	clitest.Run(t, func(t *testing.T) *configApp {
+		return new(configApp)
+	}, map[string]clitest.Case[*configApp]{
+		"unsupported shell": {
+			Args:    []string{"-completion", "tcsh"},
+			WantErr: cli.ErrInvalidArgs,
+		},
+		"help": {
+			Args:         []string{"-help"},
+			WantErr:      flag.ErrHelp,
+			WantInStderr: "-verbose",
+		},
+	})
+
+	var stderr bytes.Buffer
+	env := &cli.Env{Args: []string{"-help"}, Stdout: io.Discard, Stderr: &stderr}
+	cli.Run(cli.WithEnv(context.Background(), env), new(configApp))
+	if strings.Contains(stderr.String(), "-completion") {
+		t.Fatalf("help must not mention hidden -completion flag:\n%s", stderr.String())
+	}
+}
+
+// ownFlagsApp defines flags with the same names as flags registered by cli.
+type ownFlagsApp struct {
+	completion string
+	trace      bool
+}
+
+func (a *ownFlagsApp) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&a.completion, "completion", "", "Completion `mode`.")
+	fs.BoolVar(&a.trace, "trace", false, "Trace requests.")
+}
+
+func (a *ownFlagsApp) Run(ctx context.Context) error {
+	fmt.Fprintf(cli.GetEnv(ctx).Stdout, "completion=%s trace=%v\n", a.completion, a.trace)
+	return nil
+}
+
+func TestOwnFlags(t *testing.T) {
+	clitest.Run(t, func(t *testing.T) *ownFlagsApp {
+		return new(ownFlagsApp)
+	}, map[string]clitest.Case[*ownFlagsApp]{
+		"completion": {
+			Args:         []string{"-completion", "fuzzy"},
+			WantInStdout: "completion=fuzzy trace=false",
+		},
+		"trace": {
+			Args:         []string{"-trace"},
+			WantInStdout: "completion= trace=true",
+		},
+		"help shows completion": {
+			Args:         []string{"-help"},
+			WantErr:      flag.ErrHelp,
+			WantInStderr: "Completion mode.",
+		},
+	})
+}
+
+type ctxKey struct{}
+
+func TestContext(t *testing.T) {
+	app := cli.AppFunc(func(ctx context.Context) error {
+		env := cli.GetEnv(ctx)
+		if v, ok := ctx.Value(ctxKey{}).(string); ok {
+			fmt.Fprintf(env.Stdout, "value: %s\n", v)
+		}
+		if _, ok := ctx.Deadline(); ok {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+
+	clitest.Run(t, func(t *testing.T) cli.AppFunc { return app }, map[string]clitest.Case[cli.AppFunc]{
+		"default": {
+			WantNothingPrinted: true,
+		},
+		"value": {
+			Context: func() context.Context {
+				return context.WithValue(context.Background(), ctxKey{}, "hello")
+			},
+			WantInStdout: "value: hello",
+		},
+		"deadline": {
+			Context: func() context.Context {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				t.Cleanup(cancel)
+				return ctx
+			},
+			WantErr: context.DeadlineExceeded,
+		},
+	})
+}
@@ -9,8 +9,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"flag"
 	"io"
+	"os"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -25,6 +28,10 @@ type Case[App cli.App] struct {
 	Stdin io.Reader
 	// Env are the environment variables to set before running the application.
 	Env map[string]string
+	// Context optionally returns the parent context for the application, for
+	// example to carry values or a deadline. The Env is attached on top of
+	// it. If nil, context.Background is used.
+	Context func() context.Context
 	// WantErr is the expected error to be returned by the application, checked
 	// with errors.Is.
 	WantErr error
@@ -38,6 +45,16 @@ type Case[App cli.App] struct {
 	WantInStdout string
 	// WantInStderr is the expected substring to be present in the stderr output.
 	WantInStderr string
+	// WantStdoutRegexp is the regular expression the stdout output must match.
+	WantStdoutRegexp string
+	// WantStderrRegexp is the regular expression the stderr output must match.
+	WantStderrRegexp string
+	// WantStdoutGolden is the path to a golden file that the stdout output
+	// must match exactly. If the test binary defines the -update flag and it
+	// is set, the golden file is updated instead.
+	WantStdoutGolden string
+	// WantExitCode is the expected exit code, as determined by cli.ExitCode.
+	WantExitCode *int
 	// CheckFunc is an optional function to perform additional checks after the
 	// application has run.
 	CheckFunc func(*testing.T, App)
@@ -49,6 +66,9 @@ func Run[App cli.App](t *testing.T, setup func(*testing.T) App, cases map[string
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
+			stdoutRe := compileRegexp(t, "WantStdoutRegexp", tc.WantStdoutRegexp)
+			stderrRe := compileRegexp(t, "WantStderrRegexp", tc.WantStderrRegexp)
+
 			app := setup(t)
 
 			stdin := tc.Stdin
@@ -65,7 +85,12 @@ func Run[App cli.App](t *testing.T, setup func(*testing.T) App, cases map[string
 				Stderr: &stderr,
 			}
 
-			err := cli.Run(cli.WithEnv(context.Background(), env), app)
+			ctx := context.Background()
+			if tc.Context != nil {
+				ctx = tc.Context()
+			}
+			ctx = cli.WithEnv(ctx, env)
+			err := cli.Run(ctx, app)
 
 			// Don't use && because we want to trap all cases where err is
 			// nil.
@@ -111,6 +136,23 @@ func Run[App cli.App](t *testing.T, setup func(*testing.T) App, cases map[string
 				t.Errorf("stderr must contain %q, got: %q", tc.WantInStderr, stderr.String())
 			}
 
+			if stdoutRe != nil && !stdoutRe.Match(stdout.Bytes()) {
+				t.Errorf("stdout must match %q, got: %q", stdoutRe, stdout.String())
+			}
+			if stderrRe != nil && !stderrRe.Match(stderr.Bytes()) {
+				t.Errorf("stderr must match %q, got: %q", stderrRe, stderr.String())
+			}
+
+			if tc.WantStdoutGolden != "" {
+				checkGolden(t, tc.WantStdoutGolden, stdout.Bytes())
+			}
+
+			if tc.WantExitCode != nil {
+				if got := cli.ExitCode(ctx, err); got != *tc.WantExitCode {
+					t.Errorf("want exit code %d, got %d (error: %v)", *tc.WantExitCode, got, err)
+				}
+			}
+
 			if tc.CheckFunc != nil {
 				tc.CheckFunc(t, app)
 			}
@@ -118,6 +160,50 @@ func Run[App cli.App](t *testing.T, setup func(*testing.T) App, cases map[string
 	}
 }
 
+func compileRegexp(t *testing.T, field, expr string) *regexp.Regexp {
+	t.Helper()
+	if expr == "" {
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", field, err)
+	}
+	return re
+}
+
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if updateGolden() {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("unable to write golden file %q: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read golden file %q: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("stdout doesn't match golden file %q:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// updateGolden reports whether the -update flag is defined by the test binary
+// and set.
+func updateGolden() bool {
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	update, _ := g.Get().(bool)
+	return update
+}
+
 func getenvFunc(env map[string]string) func(string) string {
 	return func(name string) string {
 		if env == nil {
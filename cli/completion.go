@@ -0,0 +1,137 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrExitCompletion is an error indicating the application should exit after
+// printing a shell completion script.
+var ErrExitCompletion = &unprintableError{errors.New("completion flag exit")}
+
+// completionFlag registers the hidden -completion flag, unless the
+// application already defined it.
+func completionFlag(flags *flag.FlagSet) *completionValue {
+	shell := new(completionValue)
+	if flags.Lookup("completion") == nil {
+		flags.Var(shell, "completion", "Print completion script for `shell` (bash, zsh or fish).")
+	}
+	return shell
+}
+
+// completionValue is the value of the -completion flag. It has its own type
+// to tell the flag registered by this package apart from application flags
+// with the same name.
+type completionValue string
+
+func (v *completionValue) String() string     { return string(*v) }
+func (v *completionValue) Set(s string) error { *v = completionValue(s); return nil }
+
+// isHidden reports whether f is not shown in the help message and completion
+// scripts.
+func isHidden(f *flag.Flag) bool {
+	_, ok := f.Value.(*completionValue)
+	return ok
+}
+
+// printDefaults is like flags.PrintDefaults, but skips hidden flags.
+func printDefaults(flags *flag.FlagSet) {
+	visible := flag.NewFlagSet(flags.Name(), flag.ContinueOnError)
+	visible.SetOutput(flags.Output())
+	flags.VisitAll(func(f *flag.Flag) {
+		if !isHidden(f) {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	visible.PrintDefaults()
+}
+
+// writeCompletion writes a completion script for shell that completes flags
+// from flags.
+func writeCompletion(w io.Writer, shell, name string, flags *flag.FlagSet) error {
+	var visible []*flag.Flag
+	flags.VisitAll(func(f *flag.Flag) {
+		if !isHidden(f) {
+			visible = append(visible, f)
+		}
+	})
+
+	switch shell {
+	case "bash":
+		names := make([]string, len(visible))
+		for i, f := range visible {
+			names[i] = "-" + f.Name
+		}
+		fn := "_" + funcName(name)
+		fmt.Fprintf(w, "# bash completion for %s.\n", name)
+		fmt.Fprintf(w, "%s() {\n", fn)
+		fmt.Fprint(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+		fmt.Fprint(w, "\tif [[ \"$cur\" == -* ]]; then\n")
+		fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+		fmt.Fprint(w, "\telse\n")
+		fmt.Fprint(w, "\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		fmt.Fprint(w, "\tfi\n")
+		fmt.Fprint(w, "}\n")
+		fmt.Fprintf(w, "complete -F %s %s\n", fn, name)
+	case "zsh":
+		fmt.Fprintf(w, "#compdef %s\n\n", name)
+		fmt.Fprint(w, "_arguments")
+		for _, f := range visible {
+			arg, usage := flag.UnquoteUsage(f)
+			usage = zshEscaper.Replace(firstLine(usage))
+			if isBoolFlag(f) {
+				fmt.Fprintf(w, " \\\n\t'-%s[%s]'", f.Name, usage)
+				continue
+			}
+			fmt.Fprintf(w, " \\\n\t'-%s[%s]:%s:_files'", f.Name, usage, zshEscaper.Replace(arg))
+		}
+		fmt.Fprintln(w)
+	case "fish":
+		fmt.Fprintf(w, "# fish completion for %s.\n", name)
+		for _, f := range visible {
+			var arg string
+			if !isBoolFlag(f) {
+				arg = " -r"
+			}
+			_, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(w, "complete -c %s -o %s%s -d '%s'\n", name, f.Name, arg, fishEscaper.Replace(firstLine(usage)))
+		}
+	default:
+		return fmt.Errorf("%w: unsupported shell %q for completion, want bash, zsh or fish", ErrInvalidArgs, shell)
+	}
+	return nil
+}
+
+var (
+	zshEscaper  = strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+	fishEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+)
+
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// funcName turns name into a valid shell function name.
+func funcName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,87 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const assumeYesKey ctxKey = 1
+
+// WithAssumeYes returns a copy of ctx that makes [Confirm] answer yes without
+// prompting. Applications use it to implement a -yes flag.
+func WithAssumeYes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, assumeYesKey, true)
+}
+
+// Confirm asks the user a yes or no question by writing prompt to standard
+// error and reading an answer from standard input of the [Env] in ctx.
+//
+// It returns true only if the answer is "y" or "yes", ignoring case, or if
+// ctx was returned by [WithAssumeYes]. When standard input is not a
+// terminal, Confirm doesn't prompt and returns false, so scripts never
+// perform destructive actions by accident.
+func Confirm(ctx context.Context, prompt string) (bool, error) {
+	if yes, _ := ctx.Value(assumeYesKey).(bool); yes {
+		return true, nil
+	}
+
+	env := GetEnv(ctx)
+	if env.Stdin == nil || !isTerminal(env.Stdin) {
+		return false, nil
+	}
+
+	fmt.Fprintf(env.Stderr, "%s [y/N]: ", prompt)
+	answer, err := readLine(env.Stdin)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// readLine reads a line from r one byte at a time, so nothing past the line
+// is consumed and later reads from r see the rest of the input.
+func readLine(r io.Reader) (string, error) {
+	var (
+		line []byte
+		b    [1]byte
+	)
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// isTerminal reports whether r is a terminal. It's a variable for tests.
+var isTerminal = func(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
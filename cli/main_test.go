@@ -0,0 +1,208 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"go.astrophena.name/base/testutil"
+)
+
+func TestSignalFromContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("signal delivered", func(t *testing.T) {
+		var stopped bool
+		sigc := make(chan os.Signal, 1)
+		ctx, cancel := notifyContext(context.Background(), sigc, func() { stopped = true })
+		defer cancel()
+
+		sigc <- syscall.SIGTERM
+		<-ctx.Done()
+
+		testutil.AssertEqual(t, SignalFromContext(ctx), os.Signal(syscall.SIGTERM))
+		cancel()
+		testutil.AssertEqual(t, stopped, true)
+	})
+
+	t.Run("no signal", func(t *testing.T) {
+		sigc := make(chan os.Signal, 1)
+		ctx, cancel := notifyContext(context.Background(), sigc, func() {})
+		cancel()
+		<-ctx.Done()
+
+		testutil.AssertEqual(t, SignalFromContext(ctx), nil)
+	})
+
+	t.Run("derived context", func(t *testing.T) {
+		sigc := make(chan os.Signal, 1)
+		ctx, cancel := notifyContext(context.Background(), sigc, func() {})
+		defer cancel()
+		child, childCancel := context.WithCancel(ctx)
+		defer childCancel()
+
+		sigc <- os.Interrupt
+		<-child.Done()
+
+		testutil.AssertEqual(t, SignalFromContext(child), os.Interrupt)
+	})
+}
+
+type exitError int
+
+func (e exitError) Error() string { return "exit error" }
+func (e exitError) ExitCode() int { return int(e) }
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	interrupted := func() context.Context {
+		sigc := make(chan os.Signal, 1)
+		ctx, cancel := notifyContext(context.Background(), sigc, func() {})
+		t.Cleanup(cancel)
+		sigc <- os.Interrupt
+		<-ctx.Done()
+		return ctx
+	}
+
+	cases := map[string]struct {
+		ctx  context.Context
+		err  error
+		want int
+	}{
+		"no error":      {err: nil, want: 0},
+		"generic error": {err: errors.New("oops"), want: 1},
+		"invalid args":  {err: fmt.Errorf("%w: missing filename", ErrInvalidArgs), want: 2},
+		"invalid flag":  {err: parseError(errors.New("flag provided but not defined: -foo")), want: 2},
+		"help":          {err: parseError(flag.ErrHelp), want: 0},
+		"version":       {err: ErrExitVersion, want: 0},
+		"exit coder":    {err: fmt.Errorf("wrapped: %w", exitError(42)), want: 42},
+		"interrupted":   {ctx: interrupted(), err: context.Canceled, want: 130},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			testutil.AssertEqual(t, ExitCode(ctx, tc.err), tc.want)
+		})
+	}
+}
+
+func TestEnvLogf(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		env  *Env
+		want string
+	}{
+		"default": {
+			env:  &Env{},
+			want: "hello, world\n",
+		},
+		"prefix": {
+			env:  &Env{LogPrefix: "app: "},
+			want: "app: hello, world\n",
+		},
+		"flags": {
+			env:  &Env{LogPrefix: "app: ", LogFlags: log.Lmsgprefix | log.Lshortfile},
+			want: "cli.go:",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tc.env.Stderr = &buf
+			tc.env.Logf("hello, %s", "world")
+			// Changing fields after the first call has no effect.
+			tc.env.LogPrefix = "changed: "
+			tc.env.Logf("again")
+			if !strings.HasPrefix(buf.String(), tc.want) {
+				t.Fatalf("got %q, want prefix %q", buf.String(), tc.want)
+			}
+			if strings.Contains(buf.String(), "changed: ") {
+				t.Fatalf("logger must be constructed once, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	cases := map[string]struct {
+		input      string
+		terminal   bool
+		assumeYes  bool
+		want       bool
+		wantPrompt bool
+	}{
+		"yes":               {input: "y\n", terminal: true, want: true, wantPrompt: true},
+		"yes in full":       {input: "YES\n", terminal: true, want: true, wantPrompt: true},
+		"no":                {input: "n\n", terminal: true, wantPrompt: true},
+		"empty answer":      {input: "\n", terminal: true, wantPrompt: true},
+		"no newline":        {input: "y", terminal: true, want: true, wantPrompt: true},
+		"not a terminal":    {input: "y\n"},
+		"assume yes":        {assumeYes: true, want: true},
+		"assume yes no tty": {input: "n\n", assumeYes: true, want: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			old := isTerminal
+			t.Cleanup(func() { isTerminal = old })
+			isTerminal = func(io.Reader) bool { return tc.terminal }
+
+			var stderr bytes.Buffer
+			ctx := WithEnv(context.Background(), &Env{
+				Stdin:  strings.NewReader(tc.input),
+				Stderr: &stderr,
+			})
+			if tc.assumeYes {
+				ctx = WithAssumeYes(ctx)
+			}
+
+			got, err := Confirm(ctx, "Delete everything?")
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutil.AssertEqual(t, got, tc.want)
+			testutil.AssertEqual(t, stderr.String() == "Delete everything? [y/N]: ", tc.wantPrompt)
+		})
+	}
+}
+
+func TestConfirmTwice(t *testing.T) {
+	old := isTerminal
+	t.Cleanup(func() { isTerminal = old })
+	isTerminal = func(io.Reader) bool { return true }
+
+	stdin := strings.NewReader("y\nn\nrest\n")
+	ctx := WithEnv(context.Background(), &Env{Stdin: stdin, Stderr: io.Discard})
+
+	for _, want := range []bool{true, false} {
+		got, err := Confirm(ctx, "Continue?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.AssertEqual(t, got, want)
+	}
+	rest, err := io.ReadAll(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertEqual(t, string(rest), "rest\n")
+}
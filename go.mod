@@ -1,5 +1,5 @@
 module go.astrophena.name/base
 
-go 1.22.3
+go 1.25.0
 
 require github.com/google/go-cmp v0.6.0
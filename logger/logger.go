@@ -2,7 +2,7 @@
 // Use of this source code is governed by the ISC
 // license that can be found in the LICENSE.md file.
 
-// Package logger provides a basic logger type.
+// Package logger provides a basic logger type and [log/slog] handlers.
 package logger
 
 import "io"
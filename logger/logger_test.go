@@ -5,7 +5,12 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
 	"testing"
 
 	"go.astrophena.name/base/testutil"
@@ -24,3 +29,75 @@ func TestLogfWriter(t *testing.T) {
 	testutil.AssertEqual(t, logged, true)
 	testutil.AssertEqual(t, message, "hello")
 }
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewRedactHandler(slog.NewJSONHandler(&buf, nil), RedactOptions{
+		Keys:    []string{"password", "token"},
+		Pattern: regexp.MustCompile(`sk-[a-z0-9]+`),
+	})
+	l := slog.New(h).With("token", "hunter2").WithGroup("req")
+	l.Info("using key sk-abc123", "password", "hunter3", "user", "gopher", slog.Group("auth", "token", "hunter4", "hint", "key is sk-def456"))
+
+	got := buf.String()
+	for _, secret := range []string{"hunter2", "hunter3", "hunter4", "sk-abc123", "sk-def456"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("output contains secret %q: %s", secret, got)
+		}
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertEqual(t, rec["msg"], "using key [REDACTED]")
+	testutil.AssertEqual(t, rec["token"], Redacted)
+	testutil.AssertEqual(t, rec["req"], map[string]any{
+		"password": Redacted,
+		"user":     "gopher",
+		"auth": map[string]any{
+			"token": Redacted,
+			"hint":  "key is [REDACTED]",
+		},
+	})
+}
+
+func TestSampleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewSampleHandler(slog.NewTextHandler(&buf, nil), 10))
+
+	for range 100 {
+		l.Info("info")
+		l.Error("error")
+	}
+
+	testutil.AssertEqual(t, strings.Count(buf.String(), "level=INFO"), 10)
+	testutil.AssertEqual(t, strings.Count(buf.String(), "level=ERROR"), 100)
+}
+
+func TestRingHandler(t *testing.T) {
+	h := NewRingHandler(3, nil)
+	l := slog.New(h)
+
+	l.Debug("filtered out")
+	for i := range 5 {
+		l.Info(fmt.Sprintf("message %d", i), "i", i)
+	}
+
+	var msgs []string
+	for _, r := range h.Records() {
+		msgs = append(msgs, r.Message)
+	}
+	testutil.AssertEqual(t, msgs, []string{"message 2", "message 3", "message 4"})
+
+	l.With("user", "gopher").WithGroup("req").Info("grouped", "path", "/")
+	var buf bytes.Buffer
+	if err := h.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	testutil.AssertEqual(t, len(lines), 3)
+	if want := `msg=grouped user=gopher req.path=/`; !strings.Contains(lines[2], want) {
+		t.Fatalf("last line %q doesn't contain %q", lines[2], want)
+	}
+}
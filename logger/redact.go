@@ -0,0 +1,93 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+)
+
+// Redacted is the value that replaces redacted data.
+const Redacted = "[REDACTED]"
+
+// RedactOptions configure what [RedactHandler] redacts.
+type RedactOptions struct {
+	// Keys are attribute keys whose values are replaced entirely.
+	Keys []string
+	// Pattern, if not nil, matches sensitive data in the message and in
+	// string attribute values. Matches are replaced.
+	Pattern *regexp.Regexp
+}
+
+// RedactHandler is a [slog.Handler] that redacts sensitive data from records
+// before passing them to the wrapped handler.
+type RedactHandler struct {
+	h    slog.Handler
+	opts RedactOptions
+}
+
+// NewRedactHandler returns a new RedactHandler wrapping h.
+func NewRedactHandler(h slog.Handler, opts RedactOptions) *RedactHandler {
+	return &RedactHandler{h: h, opts: opts}
+}
+
+// Enabled implements the [slog.Handler] interface.
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+// Handle implements the [slog.Handler] interface.
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, h.redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.h.Handle(ctx, nr)
+}
+
+// WithAttrs implements the [slog.Handler] interface.
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redact(a)
+	}
+	return &RedactHandler{h: h.h.WithAttrs(redacted), opts: h.opts}
+}
+
+// WithGroup implements the [slog.Handler] interface.
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{h: h.h.WithGroup(name), opts: h.opts}
+}
+
+func (h *RedactHandler) redact(a slog.Attr) slog.Attr {
+	if slices.Contains(h.opts.Keys, a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]any, len(group))
+		for i, ga := range group {
+			redacted[i] = h.redact(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindString:
+		return slog.String(a.Key, h.redactString(v.String()))
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+func (h *RedactHandler) redactString(s string) string {
+	if h.opts.Pattern == nil {
+		return s
+	}
+	return h.opts.Pattern.ReplaceAllString(s, Redacted)
+}
+
+var _ slog.Handler = (*RedactHandler)(nil)
@@ -0,0 +1,140 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// RingHandler is a [slog.Handler] that keeps the most recent records in
+// memory, for example to show them on a debug page.
+type RingHandler struct {
+	ring  *ring
+	level slog.Leveler
+	goas  []groupOrAttrs
+}
+
+// groupOrAttrs is either a group name or attributes added with WithGroup or
+// WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+type ring struct {
+	mu   sync.Mutex
+	buf  []slog.Record
+	next int
+	full bool
+}
+
+// NewRingHandler returns a new RingHandler that keeps up to size most recent
+// records at level or above. If level is nil, [slog.LevelInfo] is used.
+func NewRingHandler(size int, level slog.Leveler) *RingHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &RingHandler{
+		ring:  &ring{buf: make([]slog.Record, max(size, 1))},
+		level: level,
+	}
+}
+
+// Enabled implements the [slog.Handler] interface.
+func (h *RingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements the [slog.Handler] interface.
+func (h *RingHandler) Handle(_ context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	nr.AddAttrs(h.attrs(r)...)
+
+	h.ring.mu.Lock()
+	defer h.ring.mu.Unlock()
+	h.ring.buf[h.ring.next] = nr
+	h.ring.next = (h.ring.next + 1) % len(h.ring.buf)
+	if h.ring.next == 0 {
+		h.ring.full = true
+	}
+	return nil
+}
+
+// attrs returns attributes of r combined with attributes and groups of h.
+func (h *RingHandler) attrs(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group == "" {
+			attrs = append(slices.Clip(goa.attrs), attrs...)
+			continue
+		}
+		if len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	return attrs
+}
+
+// WithAttrs implements the [slog.Handler] interface.
+func (h *RingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements the [slog.Handler] interface.
+func (h *RingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *RingHandler) with(goa groupOrAttrs) *RingHandler {
+	return &RingHandler{
+		ring:  h.ring,
+		level: h.level,
+		goas:  append(slices.Clip(h.goas), goa),
+	}
+}
+
+// Records returns a copy of retained records, from oldest to newest.
+func (h *RingHandler) Records() []slog.Record {
+	h.ring.mu.Lock()
+	defer h.ring.mu.Unlock()
+	var records []slog.Record
+	if h.ring.full {
+		records = append(records, h.ring.buf[h.ring.next:]...)
+	}
+	records = append(records, h.ring.buf[:h.ring.next]...)
+	for i, r := range records {
+		records[i] = r.Clone()
+	}
+	return records
+}
+
+// WriteText writes retained records to w, from oldest to newest, formatted by
+// [slog.TextHandler].
+func (h *RingHandler) WriteText(w io.Writer) error {
+	th := slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug - 4})
+	for _, r := range h.Records() {
+		if err := th.Handle(context.Background(), r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ slog.Handler = (*RingHandler)(nil)
@@ -0,0 +1,52 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// SampleHandler is a [slog.Handler] that passes only every Nth record below
+// [slog.LevelWarn] to the wrapped handler, dropping the rest. Warnings and
+// errors always pass.
+type SampleHandler struct {
+	h     slog.Handler
+	n     uint64
+	count *atomic.Uint64 // shared with handlers derived by WithAttrs and WithGroup
+}
+
+// NewSampleHandler returns a new SampleHandler wrapping h that passes one of
+// every n records below [slog.LevelWarn]. If n is less than 2, all records
+// pass.
+func NewSampleHandler(h slog.Handler, n int) *SampleHandler {
+	return &SampleHandler{h: h, n: uint64(max(n, 1)), count: new(atomic.Uint64)}
+}
+
+// Enabled implements the [slog.Handler] interface.
+func (h *SampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+// Handle implements the [slog.Handler] interface.
+func (h *SampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && (h.count.Add(1)-1)%h.n != 0 {
+		return nil
+	}
+	return h.h.Handle(ctx, r)
+}
+
+// WithAttrs implements the [slog.Handler] interface.
+func (h *SampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampleHandler{h: h.h.WithAttrs(attrs), n: h.n, count: h.count}
+}
+
+// WithGroup implements the [slog.Handler] interface.
+func (h *SampleHandler) WithGroup(name string) slog.Handler {
+	return &SampleHandler{h: h.h.WithGroup(name), n: h.n, count: h.count}
+}
+
+var _ slog.Handler = (*SampleHandler)(nil)
@@ -0,0 +1,85 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package request
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.astrophena.name/base/logger"
+)
+
+// Client holds defaults shared by requests to a single API, such as the base
+// URL and authentication headers.
+//
+// Since methods can't have type parameters, requests are made with the [Do]
+// function.
+type Client struct {
+	// BaseURL is prepended to relative URLs of requests.
+	BaseURL string
+	// Headers are sent with every request. Headers of a request take
+	// precedence.
+	Headers map[string]string
+	// HTTPClient is used for requests that don't set their own.
+	HTTPClient *http.Client
+	// Scrubber is used for requests that don't set their own.
+	Scrubber *strings.Replacer
+	// Logf is used for requests that don't set their own.
+	Logf logger.Logf
+}
+
+// Do makes a request with [Make], filling in the defaults from c.
+//
+// If p.URL is relative (has no scheme), it's joined with c.BaseURL.
+func Do[Response any](ctx context.Context, c *Client, p Params) (Response, error) {
+	return Make[Response](ctx, c.params(p))
+}
+
+// params returns p with the defaults from c filled in.
+func (c *Client) params(p Params) Params {
+	if u, err := url.Parse(p.URL); err != nil || !u.IsAbs() {
+		p.URL = joinURL(c.BaseURL, p.URL)
+	}
+
+	if len(c.Headers) > 0 {
+		headers := maps.Clone(c.Headers)
+		for k, v := range p.Headers {
+			// Header names are case-insensitive, so drop the default one that
+			// would collide with the override.
+			for dk := range headers {
+				if http.CanonicalHeaderKey(dk) == http.CanonicalHeaderKey(k) {
+					delete(headers, dk)
+				}
+			}
+			headers[k] = v
+		}
+		p.Headers = headers
+	}
+
+	if p.HTTPClient == nil {
+		p.HTTPClient = c.HTTPClient
+	}
+	if p.Scrubber == nil {
+		p.Scrubber = c.Scrubber
+	}
+	if p.Logf == nil {
+		p.Logf = c.Logf
+	}
+	return p
+}
+
+// joinURL joins base and path with exactly one slash between them.
+func joinURL(base, path string) string {
+	if base == "" {
+		return path
+	}
+	if path == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
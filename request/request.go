@@ -7,14 +7,24 @@ package request
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+
+	"go.astrophena.name/base/logger"
 )
 
 // DefaultClient is a [http.Client] with nice defaults.
@@ -33,13 +43,219 @@ type Params struct {
 	// Body is any data to be sent in the request body. It will be marshaled to
 	// JSON or, if it's type is url.Values, as query string with Content-Type
 	// header set to "application/x-www-form-urlencoded".
+	//
+	// If Body is a Multipart or *Multipart, it's encoded as
+	// multipart/form-data.
+	//
+	// If Body is an io.Reader, it's sent as is, without setting Content-Type.
+	// Such a body can't be replayed, so requests with it (or with a Multipart
+	// body) are never retried.
 	Body any
+	// ContentType optionally sets the Content-Type header of the request,
+	// overriding the one inferred from Body.
+	ContentType string
 	// HTTPClient is an optional custom HTTP client object to use for the request.
 	// If not provided, DefaultClient will be used.
 	HTTPClient *http.Client
+	// Timeout optionally limits the time the request, including retries and
+	// reading the response body, can take. If set, it replaces the timeout of
+	// HTTPClient, so it can be longer than the one of DefaultClient. It
+	// composes with the deadline of the context passed to Make: the earlier
+	// one wins.
+	Timeout time.Duration
 	// Scrubber is an optional strings.Replacer that scrubs unwanted data from
 	// error messages.
 	Scrubber *strings.Replacer
+	// Logf optionally logs requests and responses for debugging. Values of
+	// sensitive headers, such as Authorization, are redacted, and the
+	// Scrubber is applied to logged messages.
+	Logf logger.Logf
+	// MaxErrorBodySize is the maximum number of bytes of the response body
+	// included in the message of a *StatusError. If zero, 4 KiB is used.
+	MaxErrorBodySize int
+	// ExpectStatus is a list of status codes that indicate success. If not
+	// provided, only 200 OK is accepted. Responses with other status codes
+	// result in a *StatusError.
+	ExpectStatus []int
+	// MaxRetries is the maximum number of times the request is retried when
+	// it fails in a way deemed retryable by Retryable. Zero means no retries.
+	//
+	// Only enable retries for idempotent requests. Requests with an io.Reader
+	// Body are never retried.
+	MaxRetries int
+	// RetryBackoff optionally returns the time to wait before the retry
+	// number attempt, starting from zero. If not provided, DefaultBackoff is
+	// used. A Retry-After header of 429 and 503 responses takes precedence.
+	RetryBackoff func(attempt int) time.Duration
+	// Retryable optionally reports whether the request should be retried
+	// after getting res or err. If not provided, DefaultRetryable is used.
+	Retryable func(res *http.Response, err error) bool
+	// MaxRedirects optionally limits the number of redirects followed. If
+	// exceeded, the request fails with an error wrapping ErrTooManyRedirects.
+	// If zero, the redirect policy of HTTPClient is used.
+	MaxRedirects int
+	// FinalURL, if not nil, is set to the URL of the request that produced
+	// the response, after following redirects.
+	FinalURL *string
+}
+
+// ErrTooManyRedirects is returned by Make when the number of redirects
+// exceeds Params.MaxRedirects.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// DefaultBackoff is the default backoff strategy for retries. It waits
+// exponentially longer on each attempt, starting from 100 milliseconds and up
+// to 10 seconds.
+func DefaultBackoff(attempt int) time.Duration {
+	const (
+		base     = 100 * time.Millisecond
+		maxDelay = 10 * time.Second
+	)
+	if attempt >= 7 {
+		return maxDelay
+	}
+	return min(base<<attempt, maxDelay)
+}
+
+// DefaultRetryable is the default retry policy. It retries on transient
+// network errors, such as refused or reset connections, unexpected EOFs and
+// timeouts, and on 429 and 5xx responses. Other errors, for example
+// certificate verification failures, unknown hosts or invalid URLs, are not
+// retried.
+func DefaultRetryable(res *http.Response, err error) bool {
+	if err != nil {
+		return isTransient(err)
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
+
+// isTransient reports whether err is a network error that may go away on
+// retry.
+func isTransient(err error) bool {
+	var (
+		certErr *tls.CertificateVerificationError
+		dnsErr  *net.DNSError
+	)
+	if errors.As(err, &certErr) || errors.Is(err, ErrTooManyRedirects) {
+		return false
+	}
+	// A host that doesn't exist won't appear on retry.
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	// *url.Error implements net.Error, so only timeouts are considered.
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Multipart is a request body encoded as multipart/form-data. It's streamed
+// to the server without buffering files in memory.
+type Multipart struct {
+	// Fields are text form fields.
+	Fields map[string]string
+	// Files are files to upload.
+	Files []MultipartFile
+}
+
+// MultipartFile is a file uploaded in a multipart/form-data request body.
+type MultipartFile struct {
+	// Field is the name of the form field.
+	Field string
+	// Name is the file name.
+	Name string
+	// Content is the file content.
+	Content io.Reader
+}
+
+// encode returns a reader streaming the encoded body and its content type.
+// The reader must be read to the end or closed, otherwise the goroutine
+// writing to it leaks.
+func (m *Multipart) encode() (*io.PipeReader, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(m.write(mw))
+	}()
+	return pr, mw.FormDataContentType()
+}
+
+func (m *Multipart) write(mw *multipart.Writer) error {
+	fields := make([]string, 0, len(m.Fields))
+	for field := range m.Fields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		if err := mw.WriteField(field, m.Fields[field]); err != nil {
+			return err
+		}
+	}
+	for _, f := range m.Files {
+		w, err := mw.CreateFormFile(f.Field, f.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, f.Content); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// StatusError is returned by Make when the response has an unexpected status
+// code.
+type StatusError struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the URL of the request.
+	URL string
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// WantedStatusCodes are the status codes that were accepted.
+	WantedStatusCodes []int
+	// Body is the full, unscrubbed body of the response.
+	Body []byte
+
+	scrubber    *strings.Replacer
+	maxBodySize int
+}
+
+// defaultMaxErrorBodySize is the default value of Params.MaxErrorBodySize.
+const defaultMaxErrorBodySize = 4 << 10
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	var want string
+	if len(e.WantedStatusCodes) == 1 {
+		want = strconv.Itoa(e.WantedStatusCodes[0])
+	} else {
+		codes := make([]string, len(e.WantedStatusCodes))
+		for i, code := range e.WantedStatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		want = "one of " + strings.Join(codes, ", ")
+	}
+	// Scrub the body before truncating it, so a secret crossing the limit
+	// isn't partially leaked.
+	body := scrub(string(e.Body), e.scrubber)
+	maxSize := e.maxBodySize
+	if maxSize <= 0 {
+		maxSize = defaultMaxErrorBodySize
+	}
+	if len(body) > maxSize {
+		body = strings.ToValidUTF8(body[:maxSize], "") + "..."
+	}
+	msg := fmt.Sprintf("%s %q: want %s, got %d: ", e.Method, e.URL, want, e.StatusCode)
+	return scrub(msg, e.scrubber) + body
 }
 
 type scrubbedError struct {
@@ -66,10 +282,34 @@ func scrubErr(err error, scrubber *strings.Replacer) error {
 // It supports JSON or URL-encoded format for request bodies and JSON for
 // request responses.
 func Make[Response any](ctx context.Context, p Params) (Response, error) {
+	resp, _, err := MakeResponse[Response](ctx, p)
+	return resp, err
+}
+
+// Result holds the metadata of a response.
+type Result struct {
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// Headers are the headers of the response.
+	Headers http.Header
+}
+
+// MakeResponse is like [Make], but also returns the metadata of the response,
+// such as status code and headers. The returned *Result is nil if no response
+// was received.
+func MakeResponse[Response any](ctx context.Context, p Params) (Response, *Result, error) {
 	var resp Response
 
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
 	var (
 		data        []byte
+		reader      io.Reader
+		pipe        *io.PipeReader // multipart body, closed if never sent
 		contentType string
 	)
 	if p.Body != nil {
@@ -77,24 +317,170 @@ func Make[Response any](ctx context.Context, p Params) (Response, error) {
 		case url.Values:
 			data = []byte(v.Encode())
 			contentType = "application/x-www-form-urlencoded"
+		case Multipart:
+			pipe, contentType = v.encode()
+			reader = pipe
+		case *Multipart:
+			pipe, contentType = v.encode()
+			reader = pipe
+		case io.Reader:
+			reader = v
 		default:
 			var err error
 			data, err = json.Marshal(v)
 			if err != nil {
-				return resp, scrubErr(err, p.Scrubber)
+				return resp, nil, scrubErr(err, p.Scrubber)
 			}
 			contentType = "application/json"
 		}
 	}
 
-	var br io.Reader
-	if data != nil {
-		br = bytes.NewReader(data)
+	httpc := DefaultClient
+	if p.HTTPClient != nil {
+		httpc = p.HTTPClient
+	}
+	if p.Timeout > 0 || p.MaxRedirects > 0 {
+		c := *httpc
+		// Params.Timeout replaces the timeout of the client.
+		if p.Timeout > 0 {
+			c.Timeout = 0
+		}
+		if p.MaxRedirects > 0 {
+			c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				if len(via) > p.MaxRedirects {
+					return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, p.MaxRedirects)
+				}
+				return nil
+			}
+		}
+		httpc = &c
+	}
+	retryable := DefaultRetryable
+	if p.Retryable != nil {
+		retryable = p.Retryable
+	}
+	backoff := DefaultBackoff
+	if p.RetryBackoff != nil {
+		backoff = p.RetryBackoff
+	}
+	if p.ContentType != "" {
+		contentType = p.ContentType
+	}
+	maxRetries := p.MaxRetries
+	if reader != nil {
+		maxRetries = 0
+	}
+
+	var res *http.Response
+	for attempt := 0; ; attempt++ {
+		body := reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		req, err := newRequest(ctx, p, body, contentType)
+		if err != nil {
+			if pipe != nil {
+				pipe.CloseWithError(err)
+			}
+			return resp, nil, scrubErr(err, p.Scrubber)
+		}
+
+		start := time.Now()
+		logRequest(p, req)
+		res, err = httpc.Do(req)
+		logResponse(p, res, err, time.Since(start))
+		// Exceeding MaxRedirects is permanent, retrying won't help.
+		if attempt >= maxRetries || ctx.Err() != nil || errors.Is(err, ErrTooManyRedirects) || !retryable(res, err) {
+			if err != nil {
+				return resp, nil, scrubErr(err, p.Scrubber)
+			}
+			break
+		}
+
+		delay := backoff(attempt)
+		if res != nil {
+			if ra, ok := retryAfter(res); ok {
+				delay = ra
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, nil, scrubErr(ctx.Err(), p.Scrubber)
+		case <-timer.C:
+		}
+	}
+	defer res.Body.Close()
+
+	if p.FinalURL != nil {
+		*p.FinalURL = res.Request.URL.String()
+	}
+
+	result := &Result{
+		StatusCode: res.StatusCode,
+		Headers:    res.Header,
+	}
+
+	b, err := readBody(res)
+	if err != nil {
+		return resp, result, scrubErr(err, p.Scrubber)
+	}
+
+	wantStatus := p.ExpectStatus
+	if len(wantStatus) == 0 {
+		wantStatus = []int{http.StatusOK}
+	}
+	if !slices.Contains(wantStatus, res.StatusCode) {
+		return resp, result, &StatusError{
+			Method:            p.Method,
+			URL:               p.URL,
+			StatusCode:        res.StatusCode,
+			WantedStatusCodes: wantStatus,
+			Body:              b,
+			scrubber:          p.Scrubber,
+			maxBodySize:       p.MaxErrorBodySize,
+		}
+	}
+
+	// Responses like 204 No Content have no body to unmarshal.
+	if len(b) == 0 {
+		return resp, result, nil
+	}
+
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return resp, result, scrubErr(err, p.Scrubber)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, p.Method, p.URL, br)
+	return resp, result, nil
+}
+
+// readBody reads the response body, decompressing it if the server responded
+// with gzip encoding that wasn't already handled by the transport.
+func readBody(res *http.Response) ([]byte, error) {
+	if res.Uncompressed || !strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(res.Body)
+	}
+	zr, err := gzip.NewReader(res.Body)
 	if err != nil {
-		return resp, scrubErr(err, p.Scrubber)
+		// Empty body, e.g. for 204 No Content.
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// newRequest creates a request from p with the provided body.
+func newRequest(ctx context.Context, p Params, body io.Reader, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, p.Method, p.URL, body)
+	if err != nil {
+		return nil, err
 	}
 
 	if p.Headers != nil {
@@ -102,33 +488,72 @@ func Make[Response any](ctx context.Context, p Params) (Response, error) {
 			req.Header.Set(k, v)
 		}
 	}
-	if data != nil && contentType != "" {
+	if body != nil && contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	httpc := DefaultClient
-	if p.HTTPClient != nil {
-		httpc = p.HTTPClient
-	}
+	return req, nil
+}
 
-	res, err := httpc.Do(req)
-	if err != nil {
-		return resp, scrubErr(err, p.Scrubber)
+// sensitiveHeaders are headers whose values are redacted in logs.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+func logRequest(p Params, req *http.Request) {
+	if p.Logf == nil {
+		return
 	}
-	defer res.Body.Close()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "request: %s %s", req.Method, req.URL)
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v := strings.Join(req.Header[k], ", ")
+		if slices.Contains(sensitiveHeaders, k) {
+			v = "[REDACTED]"
+		}
+		fmt.Fprintf(&sb, "\n\t%s: %s", k, v)
+	}
+	p.Logf("%s", scrub(sb.String(), p.Scrubber))
+}
 
-	b, err := io.ReadAll(res.Body)
+func logResponse(p Params, res *http.Response, err error, duration time.Duration) {
+	if p.Logf == nil {
+		return
+	}
+	var msg string
 	if err != nil {
-		return resp, scrubErr(err, p.Scrubber)
+		msg = fmt.Sprintf("response: %s %s: error after %v: %v", p.Method, p.URL, duration, err)
+	} else {
+		msg = fmt.Sprintf("response: %s %s: %s in %v", p.Method, p.URL, res.Status, duration)
 	}
+	p.Logf("%s", scrub(msg, p.Scrubber))
+}
 
-	if res.StatusCode != http.StatusOK {
-		return resp, scrubErr(fmt.Errorf("%s %q: want 200, got %d: %s", p.Method, p.URL, res.StatusCode, b), p.Scrubber)
+func scrub(s string, scrubber *strings.Replacer) string {
+	if scrubber == nil {
+		return s
 	}
+	return scrubber.Replace(s)
+}
 
-	if err := json.Unmarshal(b, &resp); err != nil {
-		return resp, scrubErr(err, p.Scrubber)
+// retryAfter returns the delay from the Retry-After header of 429 and 503
+// responses.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
 	}
-
-	return resp, nil
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
 }
@@ -5,15 +5,26 @@
 package request_test
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"go.astrophena.name/base/request"
 )
@@ -174,3 +185,703 @@ func TestMake(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeRetry(t *testing.T) {
+	newServer := func(failures int, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+		var attempts atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if int(attempts.Add(1)) <= failures {
+				for k, v := range header {
+					w.Header()[k] = v
+				}
+				http.Error(w, "try again", status)
+				return
+			}
+			w.Write([]byte(`{"ok": true}`))
+		}))
+		t.Cleanup(ts.Close)
+		return ts, &attempts
+	}
+	noBackoff := func(int) time.Duration { return 0 }
+
+	cases := map[string]struct {
+		failures     int
+		status       int
+		header       http.Header
+		maxRetries   int
+		retryable    func(*http.Response, error) bool
+		wantErr      bool
+		wantAttempts int32
+	}{
+		"no retries by default": {
+			failures:     1,
+			status:       http.StatusInternalServerError,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		"succeeds after failures": {
+			failures:     2,
+			status:       http.StatusBadGateway,
+			maxRetries:   3,
+			wantAttempts: 3,
+		},
+		"gives up after max retries": {
+			failures:     5,
+			status:       http.StatusServiceUnavailable,
+			maxRetries:   2,
+			wantErr:      true,
+			wantAttempts: 3,
+		},
+		"honors Retry-After": {
+			failures:     1,
+			status:       http.StatusTooManyRequests,
+			header:       http.Header{"Retry-After": {"0"}},
+			maxRetries:   1,
+			wantAttempts: 2,
+		},
+		"client errors are not retried": {
+			failures:     1,
+			status:       http.StatusBadRequest,
+			maxRetries:   3,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		"custom predicate": {
+			failures:     1,
+			status:       http.StatusBadRequest,
+			maxRetries:   3,
+			retryable:    func(res *http.Response, err error) bool { return err == nil && res.StatusCode == http.StatusBadRequest },
+			wantAttempts: 2,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ts, attempts := newServer(tc.failures, tc.status, tc.header)
+			_, err := request.Make[map[string]any](context.Background(), request.Params{
+				Method:       http.MethodGet,
+				URL:          ts.URL,
+				MaxRetries:   tc.maxRetries,
+				RetryBackoff: noBackoff,
+				Retryable:    tc.retryable,
+			})
+			if tc.wantErr && err == nil {
+				t.Fatal("Make() expected error, got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("Make() error = %v", err)
+			}
+			if got := attempts.Load(); got != tc.wantAttempts {
+				t.Errorf("got %d attempts, want %d", got, tc.wantAttempts)
+			}
+		})
+	}
+
+	t.Run("respects context", func(t *testing.T) {
+		ts, _ := newServer(10, http.StatusInternalServerError, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := request.Make[map[string]any](ctx, request.Params{
+			Method:       http.MethodGet,
+			URL:          ts.URL,
+			MaxRetries:   10,
+			RetryBackoff: func(int) time.Duration { return time.Hour },
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestMakeExpectStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 1}`))
+		case "/nocontent":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	cases := map[string]struct {
+		path            string
+		expectStatus    []int
+		wantErr         bool
+		wantInErrorText string
+	}{
+		"201 rejected by default": {
+			path:            "/created",
+			wantErr:         true,
+			wantInErrorText: "want 200, got 201",
+		},
+		"201 accepted when configured": {
+			path:         "/created",
+			expectStatus: []int{http.StatusCreated},
+		},
+		"204 accepted when configured": {
+			path:         "/nocontent",
+			expectStatus: []int{http.StatusOK, http.StatusNoContent},
+		},
+		"rejected with several accepted": {
+			path:            "/nocontent",
+			expectStatus:    []int{http.StatusOK, http.StatusCreated},
+			wantErr:         true,
+			wantInErrorText: "want one of 200, 201, got 204",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := request.Make[map[string]any](context.Background(), request.Params{
+				Method:       http.MethodPost,
+				URL:          ts.URL + tc.path,
+				ExpectStatus: tc.expectStatus,
+			})
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("Make() error = %v", err)
+				}
+				return
+			}
+			var se *request.StatusError
+			if !errors.As(err, &se) {
+				t.Fatalf("want *request.StatusError, got %T: %v", err, err)
+			}
+			if !strings.Contains(err.Error(), tc.wantInErrorText) {
+				t.Fatalf("Make(): got error %q, wanted in it %q", err.Error(), tc.wantInErrorText)
+			}
+		})
+	}
+}
+
+func TestMakeResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<https://example.com/?page=2>; rel="next"`)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"message": "success"}`))
+	}))
+	defer ts.Close()
+
+	resp, res, err := request.MakeResponse[map[string]string](context.Background(), request.Params{
+		Method:       http.MethodGet,
+		URL:          ts.URL,
+		ExpectStatus: []int{http.StatusAccepted},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp["message"] != "success" {
+		t.Errorf("got response %v", resp)
+	}
+	if res.StatusCode != http.StatusAccepted {
+		t.Errorf("got status code %d, want %d", res.StatusCode, http.StatusAccepted)
+	}
+	if got, want := res.Headers.Get("Link"), `<https://example.com/?page=2>; rel="next"`; got != want {
+		t.Errorf("got Link header %q, want %q", got, want)
+	}
+}
+
+func TestMakeGzip(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"message": "compressed"}`))
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	resp, err := request.Make[map[string]string](context.Background(), request.Params{
+		Method: http.MethodGet,
+		URL:    ts.URL,
+		// Setting Accept-Encoding explicitly disables transparent
+		// decompression in http.Transport.
+		Headers: map[string]string{"Accept-Encoding": "gzip"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp["message"] != "compressed" {
+		t.Errorf("got response %v", resp)
+	}
+}
+
+func TestMakeReaderBody(t *testing.T) {
+	const payload = "line 1\nline 2\n\x00binary"
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if string(b) != payload {
+			http.Error(w, fmt.Sprintf("got body %q", b), http.StatusBadRequest)
+			return
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/octet-stream" {
+			http.Error(w, fmt.Sprintf("got Content-Type %q", got), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	_, err := request.Make[any](context.Background(), request.Params{
+		Method:       http.MethodPut,
+		URL:          ts.URL,
+		Body:         strings.NewReader(payload),
+		ContentType:  "application/octet-stream",
+		ExpectStatus: []int{http.StatusNoContent},
+		MaxRetries:   3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("got %d attempts, want 1", got)
+	}
+}
+
+func TestMakeMultipart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, fh, err := r.FormFile("image")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"caption":  r.FormValue("caption"),
+			"filename": fh.Filename,
+			"content":  string(b),
+		})
+	}))
+	defer ts.Close()
+
+	resp, err := request.Make[map[string]string](context.Background(), request.Params{
+		Method: http.MethodPost,
+		URL:    ts.URL,
+		Body: request.Multipart{
+			Fields: map[string]string{"caption": "A cat."},
+			Files: []request.MultipartFile{
+				{Field: "image", Name: "cat.png", Content: strings.NewReader("meow")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"caption":  "A cat.",
+		"filename": "cat.png",
+		"content":  "meow",
+	}
+	for k, v := range want {
+		if resp[k] != v {
+			t.Errorf("%s: got %q, want %q", k, resp[k], v)
+		}
+	}
+}
+
+func TestMakeStatusErrorBody(t *testing.T) {
+	const secret = "hunter2"
+
+	cases := map[string]struct {
+		body        string
+		maxSize     int
+		wantMaxSize int
+		wantInMsg   string
+	}{
+		"default limit": {
+			body:        "token=" + secret + " " + strings.Repeat("x", 10<<10),
+			wantMaxSize: 4 << 10,
+			wantInMsg:   "token=[EXPUNGED]",
+		},
+		"custom limit": {
+			body:        "token=" + secret + " " + strings.Repeat("x", 10<<10),
+			maxSize:     100,
+			wantMaxSize: 100,
+			wantInMsg:   "token=[EXPUNGED]",
+		},
+		"secret crossing limit": {
+			body:        strings.Repeat("a", (4<<10)-6) + secret + strings.Repeat("x", 100),
+			wantMaxSize: 4 << 10,
+			wantInMsg:   "aaa[EXP",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, tc.body, http.StatusForbidden)
+			}))
+			defer ts.Close()
+
+			_, err := request.Make[any](context.Background(), request.Params{
+				Method:           http.MethodGet,
+				URL:              ts.URL,
+				Scrubber:         strings.NewReplacer(secret, "[EXPUNGED]"),
+				MaxErrorBodySize: tc.maxSize,
+			})
+			var se *request.StatusError
+			if !errors.As(err, &se) {
+				t.Fatalf("want *request.StatusError, got %T: %v", err, err)
+			}
+			for _, msg := range []string{err.Error(), se.Error()} {
+				for i := 3; i <= len(secret); i++ {
+					if strings.Contains(msg, secret[:i]) {
+						t.Fatalf("error message contains part of the secret %q: %q", secret[:i], msg[max(0, len(msg)-50):])
+					}
+				}
+				if !strings.Contains(msg, tc.wantInMsg) {
+					t.Fatalf("error message isn't scrubbed: %q", msg[max(0, len(msg)-50):])
+				}
+				if !strings.HasSuffix(msg, "...") {
+					t.Fatalf("error message isn't truncated: %q", msg[max(0, len(msg)-50):])
+				}
+				if len(msg) > tc.wantMaxSize+len(ts.URL)+100 {
+					t.Fatalf("error message is too long: %d bytes", len(msg))
+				}
+			}
+			if !strings.Contains(string(se.Body), secret) || len(se.Body) < len(tc.body) {
+				t.Fatal("StatusError.Body must contain the full body")
+			}
+		})
+	}
+}
+
+func TestMakeTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	_, err := request.Make[any](context.Background(), request.Params{
+		Method:  http.MethodGet,
+		URL:     ts.URL,
+		Timeout: 50 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+
+	t.Run("longer than client timeout", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte(`{}`))
+		}))
+		defer ts.Close()
+
+		_, err := request.Make[any](context.Background(), request.Params{
+			Method:     http.MethodGet,
+			URL:        ts.URL,
+			HTTPClient: &http.Client{Timeout: 10 * time.Millisecond},
+			Timeout:    5 * time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestMakeLogf(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var logs strings.Builder
+	_, err := request.Make[any](context.Background(), request.Params{
+		Method: http.MethodGet,
+		URL:    ts.URL + "/?key=sekrit",
+		Headers: map[string]string{
+			"Authorization": "Bearer hunter2",
+			"X-Api-Key":     "sekrit",
+			"X-Test":        "visible",
+		},
+		Scrubber: strings.NewReplacer("sekrit", "[EXPUNGED]"),
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(&logs, format+"\n", args...)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := logs.String()
+	for _, secret := range []string{"hunter2", "sekrit"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("logs contain %q:\n%s", secret, got)
+		}
+	}
+	for _, want := range []string{"request: GET", "Authorization: [REDACTED]", "X-Test: visible", "200 OK"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("logs don't contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":   r.URL.RequestURI(),
+			"auth":   r.Header.Get("Authorization"),
+			"accept": r.Header.Get("Accept"),
+			"extra":  r.Header.Get("X-Extra"),
+		})
+	}))
+	defer ts.Close()
+
+	c := &request.Client{
+		BaseURL: ts.URL + "/api/",
+		Headers: map[string]string{
+			"Authorization": "Bearer default",
+			"Accept":        "application/json",
+		},
+	}
+
+	cases := map[string]struct {
+		p    request.Params
+		want map[string]string
+	}{
+		"defaults": {
+			p: request.Params{Method: http.MethodGet, URL: "/users"},
+			want: map[string]string{
+				"path":   "/api/users",
+				"auth":   "Bearer default",
+				"accept": "application/json",
+				"extra":  "",
+			},
+		},
+		"override header": {
+			p: request.Params{
+				Method: http.MethodGet,
+				URL:    "users?page=2",
+				Headers: map[string]string{
+					"authorization": "Bearer override",
+					"X-Extra":       "yes",
+				},
+			},
+			want: map[string]string{
+				"path":   "/api/users?page=2",
+				"auth":   "Bearer override",
+				"accept": "application/json",
+				"extra":  "yes",
+			},
+		},
+		"URL in query": {
+			p: request.Params{Method: http.MethodGet, URL: "search?next=https://example.com"},
+			want: map[string]string{
+				"path":   "/api/search?next=https://example.com",
+				"auth":   "Bearer default",
+				"accept": "application/json",
+				"extra":  "",
+			},
+		},
+		"absolute URL": {
+			p: request.Params{Method: http.MethodGet, URL: ts.URL + "/other"},
+			want: map[string]string{
+				"path":   "/other",
+				"auth":   "Bearer default",
+				"accept": "application/json",
+				"extra":  "",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := request.Do[map[string]string](context.Background(), c, tc.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tc.want {
+				if got[k] != want {
+					t.Errorf("%s: want %q, got %q", k, want, got[k])
+				}
+			}
+		})
+	}
+
+	if c.Headers["Authorization"] != "Bearer default" || len(c.Headers) != 2 {
+		t.Fatalf("client headers were modified: %v", c.Headers)
+	}
+}
+
+func TestMakeRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/first":
+			http.Redirect(w, r, "/second", http.StatusFound)
+		case "/second":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/final":
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	cases := map[string]struct {
+		maxRedirects int
+		wantErr      error
+	}{
+		"default policy": {},
+		"within limit":   {maxRedirects: 2},
+		"exceeded":       {maxRedirects: 1, wantErr: request.ErrTooManyRedirects},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var finalURL string
+			_, err := request.Make[map[string]any](context.Background(), request.Params{
+				Method:       http.MethodGet,
+				URL:          ts.URL + "/first",
+				HTTPClient:   ts.Client(),
+				MaxRedirects: tc.maxRedirects,
+				FinalURL:     &finalURL,
+			})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("want %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := ts.URL + "/final"; finalURL != want {
+				t.Fatalf("want final URL %q, got %q", want, finalURL)
+			}
+		})
+	}
+}
+
+func TestMakeRedirectsNotRetried(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	_, err := request.Make[map[string]any](context.Background(), request.Params{
+		Method:       http.MethodGet,
+		URL:          ts.URL,
+		HTTPClient:   ts.Client(),
+		MaxRedirects: 1,
+		MaxRetries:   3,
+		RetryBackoff: func(int) time.Duration { return 0 },
+	})
+	if !errors.Is(err, request.ErrTooManyRedirects) {
+		t.Fatalf("want ErrTooManyRedirects, got %v", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("want 2 server hits, got %d", got)
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: err}
+	}
+
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"connection refused": {
+			err:  urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			want: true,
+		},
+		"connection reset": {
+			err:  urlErr(syscall.ECONNRESET),
+			want: true,
+		},
+		"unexpected EOF": {
+			err:  urlErr(io.ErrUnexpectedEOF),
+			want: true,
+		},
+		"timeout": {
+			err:  urlErr(context.DeadlineExceeded),
+			want: true,
+		},
+		"no such host": {
+			err: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "x.invalid", IsNotFound: true}}),
+		},
+		"temporary DNS failure": {
+			err:  urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}}),
+			want: true,
+		},
+		"certificate": {
+			err: urlErr(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}),
+		},
+		"too many redirects": {
+			err: urlErr(fmt.Errorf("%w: stopped after 1", request.ErrTooManyRedirects)),
+		},
+		"unsupported scheme": {
+			err: urlErr(errors.New(`unsupported protocol scheme "ftp"`)),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := request.DefaultRetryable(nil, tc.err); got != tc.want {
+				t.Errorf("DefaultRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("real certificate error", func(t *testing.T) {
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+		ts.StartTLS()
+		defer ts.Close()
+		res, err := request.DefaultClient.Get(ts.URL)
+		if err == nil {
+			res.Body.Close()
+			t.Fatal("want certificate error, got none")
+		}
+		if request.DefaultRetryable(nil, err) {
+			t.Fatalf("certificate error %v must not be retryable", err)
+		}
+	})
+}
+
+func TestMakeMultipartInvalidRequest(t *testing.T) {
+	// synctest.Test fails if the goroutine writing the body is left blocked.
+	synctest.Test(t, func(t *testing.T) {
+		_, err := request.Make[any](context.Background(), request.Params{
+			Method: "BAD METHOD",
+			URL:    "http://example.com",
+			Body: &request.Multipart{
+				Fields: map[string]string{"caption": "A cat."},
+				Files: []request.MultipartFile{
+					{Field: "image", Name: "cat.jpg", Content: strings.NewReader("meow")},
+				},
+			},
+		})
+		if err == nil {
+			t.Fatal("want error for invalid method, got none")
+		}
+		synctest.Wait()
+	})
+}
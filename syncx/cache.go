@@ -0,0 +1,149 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package syncx
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is an in-memory cache of limited size with per-entry expiration, safe
+// for concurrent use. When the cache is full, the least recently used entry is
+// evicted.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxSize int
+	ll      *list.List // of *cacheEntry[K, V], most recently used first
+	items   map[K]*list.Element
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	val     V
+	expires time.Time // zero if never expires
+}
+
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// NewCache returns a new Cache that holds up to maxSize entries.
+//
+// Expired entries are removed lazily on access. If sweepInterval is positive,
+// a background goroutine also removes them every sweepInterval, until Close is
+// called.
+func NewCache[K comparable, V any](maxSize int, sweepInterval time.Duration) *Cache[K, V] {
+	c := &Cache[K, V]{
+		maxSize: max(maxSize, 1),
+		ll:      list.New(),
+		items:   make(map[K]*list.Element),
+		stop:    make(chan struct{}),
+	}
+	if sweepInterval > 0 {
+		go c.sweep(sweepInterval)
+	}
+	return c
+}
+
+// Get returns the value stored for key, if it exists and hasn't expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*cacheEntry[K, V])
+	if e.expired(time.Now()) {
+		c.remove(el)
+		var zero V
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	return e.val, true
+}
+
+// Set stores val for key, expiring after ttl. If ttl is not positive, the
+// entry never expires, but still can be evicted.
+func (c *Cache[K, V]) Set(key K, val V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*cacheEntry[K, V])
+		e.val, e.expires = val, expires
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry[K, V]{key: key, val: val, expires: expires})
+	for c.ll.Len() > c.maxSize {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Delete removes the entry for key.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// that weren't removed yet.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Close stops the background goroutine removing expired entries, if any.
+func (c *Cache[K, V]) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry[K, V]).key)
+}
+
+func (c *Cache[K, V]) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.removeExpired()
+		}
+	}
+}
+
+func (c *Cache[K, V]) removeExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cacheEntry[K, V]).expired(now) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
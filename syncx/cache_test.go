@@ -0,0 +1,98 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package syncx
+
+import (
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"go.astrophena.name/base/testutil"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("expiry", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			c := NewCache[string, int](10, 0)
+			c.Set("short", 1, time.Minute)
+			c.Set("forever", 2, 0)
+
+			time.Sleep(59 * time.Second)
+			v, ok := c.Get("short")
+			testutil.AssertEqual(t, ok, true)
+			testutil.AssertEqual(t, v, 1)
+
+			time.Sleep(time.Second)
+			_, ok = c.Get("short")
+			testutil.AssertEqual(t, ok, false)
+			testutil.AssertEqual(t, c.Len(), 1)
+
+			time.Sleep(24 * time.Hour)
+			v, ok = c.Get("forever")
+			testutil.AssertEqual(t, ok, true)
+			testutil.AssertEqual(t, v, 2)
+		})
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		c := NewCache[string, int](3, 0)
+		c.Set("a", 1, 0)
+		c.Set("b", 2, 0)
+		c.Set("c", 3, 0)
+		c.Get("a")       // "b" is now least recently used.
+		c.Set("d", 4, 0) // Evicts "b".
+		c.Set("c", 5, 0) // Updates "c", "a" is now least recently used.
+		c.Set("e", 6, 0) // Evicts "a".
+
+		testutil.AssertEqual(t, c.Len(), 3)
+		for key, want := range map[string]bool{"a": false, "b": false, "c": true, "d": true, "e": true} {
+			if _, ok := c.Get(key); ok != want {
+				t.Errorf("Get(%q): got present = %v, want %v", key, ok, want)
+			}
+		}
+		v, _ := c.Get("c")
+		testutil.AssertEqual(t, v, 5)
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			c := NewCache[int, int](10, time.Minute)
+			defer c.Close()
+			for i := range 5 {
+				c.Set(i, i, 30*time.Second)
+			}
+			c.Set(5, 5, 0)
+
+			time.Sleep(time.Minute)
+			synctest.Wait()
+			testutil.AssertEqual(t, c.Len(), 1)
+		})
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			c := NewCache[int, int](50, time.Second)
+			defer c.Close()
+			var wg sync.WaitGroup
+			for i := range 100 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.Set(i, i, time.Duration(i)*time.Millisecond)
+					c.Get(i - 1)
+					time.Sleep(time.Duration(i) * time.Millisecond)
+					c.Delete(i - 2)
+				}()
+			}
+			wg.Wait()
+			if n := c.Len(); n > 50 {
+				t.Fatalf("cache has %d entries, want at most 50", n)
+			}
+		})
+	})
+}
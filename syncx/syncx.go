@@ -5,7 +5,11 @@
 // Package syncx contains useful synchronization primitives.
 package syncx
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 // Protect wraps T into [Protected].
 func Protect[T any](val T) *Protected[T] { return &Protected[T]{val: val} }
@@ -32,6 +36,21 @@ func (p *Protected[T]) Access(f func(T)) {
 	f(p.val)
 }
 
+// Update atomically replaces the value protected by p with the first result of
+// f, called with the current value under a write lock, and returns the second
+// result of f.
+//
+// For example, to increment a counter and get the new value:
+//
+//	n := syncx.Update(p, func(n int) (int, int) { return n + 1, n + 1 })
+func Update[T, R any](p *Protected[T], f func(T) (T, R)) R {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var r R
+	p.val, r = f(p.val)
+	return r
+}
+
 // Lazy represents a lazily computed value.
 type Lazy[T any] struct {
 	once sync.Once
@@ -85,3 +104,127 @@ func (lwg *LimitedWaitGroup) Done() {
 
 // Wait blocks until the counter of the LimitedWaitGroup becomes zero.
 func (lwg *LimitedWaitGroup) Wait() { lwg.wg.Wait() }
+
+// Debouncer coalesces bursts of events: the callback is called once the events
+// stop arriving for a specified delay.
+type Debouncer struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	f       func()
+	timer   *time.Timer
+	stopped bool
+}
+
+// NewDebouncer returns a new Debouncer that calls f in its own goroutine after
+// delay has passed since the last call to Trigger.
+func NewDebouncer(delay time.Duration, f func()) *Debouncer {
+	return &Debouncer{delay: delay, f: f}
+}
+
+// Trigger schedules a call to the callback, postponing the previously
+// scheduled one, if any. It does nothing after Stop is called.
+func (d *Debouncer) Trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	if d.timer == nil {
+		d.timer = time.AfterFunc(d.delay, d.f)
+		return
+	}
+	d.timer.Reset(d.delay)
+}
+
+// Stop cancels the scheduled call to the callback, if any, and makes future
+// calls to Trigger no-op.
+func (d *Debouncer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// ErrGroup is a collection of goroutines working on subtasks of a common task,
+// like [LimitedWaitGroup], but collecting errors. The first error cancels the
+// context returned by [NewErrGroup].
+type ErrGroup struct {
+	lwg    *LimitedWaitGroup
+	cancel context.CancelCauseFunc
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewErrGroup returns a new ErrGroup that limits the number of concurrently
+// working goroutines to limit, and a context derived from ctx that is canceled
+// when any goroutine returns an error or Wait returns.
+func NewErrGroup(ctx context.Context, limit int) (*ErrGroup, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &ErrGroup{
+		lwg:    NewLimitedWaitGroup(limit),
+		cancel: cancel,
+	}, ctx
+}
+
+// Go calls f in a new goroutine. It blocks if the number of active goroutines
+// reaches the concurrency limit.
+func (g *ErrGroup) Go(f func() error) {
+	g.lwg.Add(1)
+	go func() {
+		defer g.lwg.Done()
+		if err := f(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait blocks until all goroutines started by Go have returned, and returns
+// the first error returned by them, if any.
+func (g *ErrGroup) Wait() error {
+	g.lwg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
+
+// Event is a one-shot signal that many goroutines can wait for. The zero value
+// is ready to use.
+type Event struct {
+	initOnce sync.Once
+	fireOnce sync.Once
+	ch       chan struct{}
+}
+
+func (e *Event) init() {
+	e.initOnce.Do(func() { e.ch = make(chan struct{}) })
+}
+
+// Fire fires the event, unblocking all waiters. Subsequent calls do nothing.
+func (e *Event) Fire() {
+	e.init()
+	e.fireOnce.Do(func() { close(e.ch) })
+}
+
+// Wait blocks until the event is fired.
+func (e *Event) Wait() { <-e.Done() }
+
+// Done returns a channel that's closed when the event is fired.
+func (e *Event) Done() <-chan struct{} {
+	e.init()
+	return e.ch
+}
+
+// Fired reports whether the event has been fired.
+func (e *Event) Fired() bool {
+	select {
+	case <-e.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -5,10 +5,13 @@
 package syncx
 
 import (
+	"context"
 	"errors"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"go.astrophena.name/base/testutil"
@@ -56,6 +59,30 @@ func TestProtected(t *testing.T) {
 		p.RAccess(func(val *int) { result = *val })
 		testutil.AssertEqual(t, result, 100)
 	})
+
+	t.Run("update", func(t *testing.T) {
+		p := Protect(0)
+		results := make([]int, 100)
+		var wg sync.WaitGroup
+		for i := range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = Update(p, func(n int) (int, int) { return n + 1, n + 1 })
+			}()
+		}
+		wg.Wait()
+
+		var result int
+		p.RAccess(func(val int) { result = val })
+		testutil.AssertEqual(t, result, 100)
+
+		// Each Update must have observed a distinct value.
+		slices.Sort(results)
+		for i, r := range results {
+			testutil.AssertEqual(t, r, i+1)
+		}
+	})
 }
 
 func TestLazy(t *testing.T) {
@@ -166,3 +193,166 @@ func TestLimitedWaitGroup(t *testing.T) {
 		testutil.AssertEqual(t, int(maxConcurrent), concurrency)
 	})
 }
+
+func TestDebouncer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fires once after burst", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var calls atomic.Int32
+			d := NewDebouncer(100*time.Millisecond, func() { calls.Add(1) })
+			defer d.Stop()
+
+			for range 10 {
+				d.Trigger()
+				time.Sleep(50 * time.Millisecond)
+			}
+			synctest.Wait()
+			testutil.AssertEqual(t, calls.Load(), int32(0))
+
+			time.Sleep(100 * time.Millisecond)
+			synctest.Wait()
+			testutil.AssertEqual(t, calls.Load(), int32(1))
+
+			d.Trigger()
+			time.Sleep(100 * time.Millisecond)
+			synctest.Wait()
+			testutil.AssertEqual(t, calls.Load(), int32(2))
+		})
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var calls atomic.Int32
+			d := NewDebouncer(100*time.Millisecond, func() { calls.Add(1) })
+			d.Trigger()
+			d.Stop()
+			d.Trigger()
+			time.Sleep(time.Second)
+			synctest.Wait()
+			testutil.AssertEqual(t, calls.Load(), int32(0))
+		})
+	})
+}
+
+func TestErrGroup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("all succeed", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			g, ctx := NewErrGroup(context.Background(), 3)
+			var done atomic.Int32
+			for range 10 {
+				g.Go(func() error {
+					time.Sleep(time.Second)
+					done.Add(1)
+					return nil
+				})
+			}
+			if err := g.Wait(); err != nil {
+				t.Fatal(err)
+			}
+			testutil.AssertEqual(t, done.Load(), int32(10))
+			// Context is canceled after Wait returns.
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				t.Fatalf("context must be canceled, got %v", ctx.Err())
+			}
+		})
+	})
+
+	t.Run("one fails and cancels others", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			errFail := errors.New("failed")
+			g, ctx := NewErrGroup(context.Background(), 5)
+			var canceled atomic.Int32
+			for i := range 5 {
+				g.Go(func() error {
+					if i == 2 {
+						time.Sleep(time.Second)
+						return errFail
+					}
+					select {
+					case <-ctx.Done():
+						canceled.Add(1)
+						return ctx.Err()
+					case <-time.After(time.Hour):
+						return nil
+					}
+				})
+			}
+			err := g.Wait()
+			if !errors.Is(err, errFail) {
+				t.Fatalf("want %v, got %v", errFail, err)
+			}
+			testutil.AssertEqual(t, canceled.Load(), int32(4))
+			if !errors.Is(context.Cause(ctx), errFail) {
+				t.Fatalf("want cause %v, got %v", errFail, context.Cause(ctx))
+			}
+		})
+	})
+
+	t.Run("limits concurrency", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			const limit = 3
+			g, _ := NewErrGroup(context.Background(), limit)
+			var running, maxRunning atomic.Int32
+			for range 20 {
+				g.Go(func() error {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						cur := maxRunning.Load()
+						if n <= cur || maxRunning.CompareAndSwap(cur, n) {
+							break
+						}
+					}
+					time.Sleep(time.Second)
+					return nil
+				})
+			}
+			if err := g.Wait(); err != nil {
+				t.Fatal(err)
+			}
+			testutil.AssertEqual(t, maxRunning.Load(), int32(limit))
+		})
+	})
+}
+
+func TestEvent(t *testing.T) {
+	t.Parallel()
+
+	t.Run("multiple waiters", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var e Event
+			var woken atomic.Int32
+			for range 10 {
+				go func() {
+					e.Wait()
+					woken.Add(1)
+				}()
+			}
+			synctest.Wait()
+			testutil.AssertEqual(t, woken.Load(), int32(0))
+			testutil.AssertEqual(t, e.Fired(), false)
+
+			e.Fire()
+			e.Fire() // Must be idempotent.
+			synctest.Wait()
+			testutil.AssertEqual(t, woken.Load(), int32(10))
+			testutil.AssertEqual(t, e.Fired(), true)
+		})
+	})
+
+	t.Run("wait after fire", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var e Event
+			e.Fire()
+			e.Wait()
+			select {
+			case <-e.Done():
+			default:
+				t.Fatal("Done channel must be closed")
+			}
+		})
+	})
+}
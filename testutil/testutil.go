@@ -33,7 +33,7 @@ func UnmarshalJSON[V any](t *testing.T, b []byte) V {
 }
 
 // AssertContains fails the test if v is not present in s.
-func AssertContains[S ~[]V, V comparable](t *testing.T, s S, v V) {
+func AssertContains[S ~[]V, V comparable](t testing.TB, s S, v V) {
 	t.Helper()
 	if !slices.Contains(s, v) {
 		t.Fatalf("%v is not present in %v", v, s)
@@ -41,25 +41,78 @@ func AssertContains[S ~[]V, V comparable](t *testing.T, s S, v V) {
 }
 
 // AssertNotContains fails the test if v is present in s.
-func AssertNotContains[S ~[]V, V comparable](t *testing.T, s S, v V) {
+func AssertNotContains[S ~[]V, V comparable](t testing.TB, s S, v V) {
 	t.Helper()
 	if slices.Contains(s, v) {
 		t.Fatalf("%v is present in %v", v, s)
 	}
 }
 
+// AssertStringContains fails the test if substr is not present in s.
+func AssertStringContains(t testing.TB, s, substr string) {
+	t.Helper()
+	if !strings.Contains(s, substr) {
+		t.Fatalf("%q is not present in %q", substr, s)
+	}
+}
+
 // AssertEqual compares two values and if they differ, fails the test and
 // prints the difference between them.
-func AssertEqual(t *testing.T, got, want any) {
+//
+// The difference is computed field by field and line by line for multi-line
+// strings, so only the differing parts of large values are printed.
+func AssertEqual(t testing.TB, got, want any) {
 	t.Helper()
 	if diff := cmp.Diff(want, got); diff != "" {
-		t.Fatalf("(-got +want):\n%s", diff)
+		t.Fatalf("(-want +got):\n%s", diff)
+	}
+}
+
+// AssertNotEqual compares two values and if they are equal, fails the test and
+// prints them.
+func AssertNotEqual(t testing.TB, got, want any) {
+	t.Helper()
+	if cmp.Equal(got, want) {
+		t.Fatalf("got %v, want anything else", got)
+	}
+}
+
+// AssertNoError fails the test if err is not nil.
+func AssertNoError(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("got error: %v, want nil", err)
+	}
+}
+
+// AssertError fails the test if err is nil.
+func AssertError(t testing.TB, err error) {
+	t.Helper()
+	if err == nil {
+		t.Fatal("got nil error, want non-nil")
+	}
+}
+
+// AssertJSONEqual fails the test if got and want are not semantically equal
+// JSON documents, ignoring formatting and order of object keys, and prints the
+// difference between them.
+func AssertJSONEqual(t testing.TB, got, want []byte) {
+	t.Helper()
+	var gotv, wantv any
+	if err := json.Unmarshal(got, &gotv); err != nil {
+		t.Fatalf("got invalid JSON: %v\n%s", err, got)
+	}
+	if err := json.Unmarshal(want, &wantv); err != nil {
+		t.Fatalf("want invalid JSON: %v\n%s", err, want)
+	}
+	if diff := cmp.Diff(wantv, gotv); diff != "" {
+		t.Fatalf("JSON differs (-want +got):\n%s", diff)
 	}
 }
 
 // AssertErrorType asserts that the got error is of the same type as the want
 // error. It does not compare error messages or values, only the types.
-func AssertErrorType(t *testing.T, got, want error) {
+func AssertErrorType(t testing.TB, got, want error) {
 	t.Helper()
 	gotErr := reflect.Zero(reflect.TypeOf(want)).Interface()
 	fail := func() {
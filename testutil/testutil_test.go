@@ -0,0 +1,174 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recordingTB is a testing.TB that records failures.
+type recordingTB struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failed = true
+	r.msg = fmt.Sprintf(format, args...)
+}
+
+func (r *recordingTB) Fatal(args ...any) {
+	r.failed = true
+	r.msg = fmt.Sprint(args...)
+	runtime.Goexit()
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// record runs f with a recordingTB in a separate goroutine, so f can be
+// stopped by Fatal, and returns the recordingTB.
+func record(t *testing.T, f func(tb testing.TB)) *recordingTB {
+	r := &recordingTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test")
+
+	cases := map[string]struct {
+		f          func(tb testing.TB)
+		wantFail   bool
+		wantInMsgs []string
+	}{
+		"AssertStringContains pass": {
+			f: func(tb testing.TB) { AssertStringContains(tb, "hello, world", "world") },
+		},
+		"AssertStringContains fail": {
+			f:          func(tb testing.TB) { AssertStringContains(tb, "hello, world", "gopher") },
+			wantFail:   true,
+			wantInMsgs: []string{`"gopher"`, `"hello, world"`},
+		},
+		"AssertNotEqual pass": {
+			f: func(tb testing.TB) { AssertNotEqual(tb, 1, 2) },
+		},
+		"AssertNotEqual fail": {
+			f:          func(tb testing.TB) { AssertNotEqual(tb, []string{"a"}, []string{"a"}) },
+			wantFail:   true,
+			wantInMsgs: []string{"[a]"},
+		},
+		"AssertNoError pass": {
+			f: func(tb testing.TB) { AssertNoError(tb, nil) },
+		},
+		"AssertNoError fail": {
+			f:          func(tb testing.TB) { AssertNoError(tb, errTest) },
+			wantFail:   true,
+			wantInMsgs: []string{"test"},
+		},
+		"AssertError pass": {
+			f: func(tb testing.TB) { AssertError(tb, errTest) },
+		},
+		"AssertError fail": {
+			f:        func(tb testing.TB) { AssertError(tb, nil) },
+			wantFail: true,
+		},
+		"AssertJSONEqual reordered": {
+			f: func(tb testing.TB) {
+				AssertJSONEqual(tb, []byte(`{"b": [1, 2], "a": {"y": null, "x": "1"}}`), []byte(`{
+					"a": {"x": "1", "y": null},
+					"b": [1, 2]
+				}`))
+			},
+		},
+		"AssertJSONEqual different": {
+			f: func(tb testing.TB) {
+				AssertJSONEqual(tb, []byte(`{"a": 1, "b": [2, 1]}`), []byte(`{"a": 1, "b": [1, 2]}`))
+			},
+			wantFail:   true,
+			wantInMsgs: []string{"(-want +got)", `"b"`},
+		},
+		"AssertJSONEqual invalid": {
+			f:          func(tb testing.TB) { AssertJSONEqual(tb, []byte(`{`), []byte(`{}`)) },
+			wantFail:   true,
+			wantInMsgs: []string{"got invalid JSON"},
+		},
+		"AssertEqual pass": {
+			f: func(tb testing.TB) { AssertEqual(tb, map[string]int{"a": 1}, map[string]int{"a": 1}) },
+		},
+		"AssertEqual fail": {
+			f:          func(tb testing.TB) { AssertEqual(tb, 1, 2) },
+			wantFail:   true,
+			wantInMsgs: []string{"1", "2"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := record(t, tc.f)
+			if r.failed != tc.wantFail {
+				t.Fatalf("failed = %v, want %v (message: %q)", r.failed, tc.wantFail, r.msg)
+			}
+			for _, want := range tc.wantInMsgs {
+				if !strings.Contains(r.msg, want) {
+					t.Errorf("failure message %q doesn't contain %q", r.msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertEqualDiff(t *testing.T) {
+	t.Parallel()
+
+	type policy struct {
+		DefaultSrc []string
+		ScriptSrc  []string
+		ImgSrc     []string
+		Upgrade    bool
+	}
+	got := policy{
+		DefaultSrc: []string{"'self'"},
+		ScriptSrc:  []string{"'self'", "https://cdn.example.com"},
+		ImgSrc:     []string{"'self'", "data:"},
+	}
+	want := got
+	want.ImgSrc = []string{"'self'"}
+
+	r := record(t, func(tb testing.TB) { AssertEqual(tb, got, want) })
+	if !r.failed {
+		t.Fatal("AssertEqual must fail")
+	}
+	for _, wantInMsg := range []string{"(-want +got)", "ImgSrc: []string{"} {
+		if !strings.Contains(r.msg, wantInMsg) {
+			t.Errorf("failure message doesn't contain %q:\n%s", wantInMsg, r.msg)
+		}
+	}
+	var added []string
+	for _, line := range strings.Split(r.msg, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "+") {
+			added = append(added, strings.TrimSpace(line))
+		}
+	}
+	if len(added) != 1 || !strings.Contains(added[0], `"data:"`) {
+		t.Errorf("want only the differing element to be marked as added, got %q", added)
+	}
+}
@@ -0,0 +1,188 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package txtar
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS returns a read-only file system view of the archive, where each file is a
+// regular file at its name. Directories are synthesized from file names.
+//
+// The returned file system reflects the archive at the time of the call.
+// Files with names that are not valid by [fs.ValidPath] are skipped. If the
+// archive contains several files with the same name, the first one is used.
+// Likewise, if a name is used both as a file and as a directory (as in "a" and
+// "a/b"), whichever comes first wins and the conflicting files are skipped.
+func (a *Archive) FS() fs.FS {
+	afs := &archiveFS{
+		files: make(map[string]File),
+		dirs:  map[string][]fs.DirEntry{".": nil},
+	}
+	for _, f := range a.Files {
+		name := path.Clean(f.Name)
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		if _, ok := afs.files[name]; ok {
+			continue
+		}
+		if _, ok := afs.dirs[name]; ok {
+			continue
+		}
+		if afs.hasFileParent(name) {
+			continue
+		}
+		afs.files[name] = f
+		afs.addEntry(name, fileInfo{name: path.Base(name), size: int64(len(f.Data)), mode: f.Mode.Perm()})
+	}
+	for _, entries := range afs.dirs {
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	}
+	return afs
+}
+
+type archiveFS struct {
+	files map[string]File
+	dirs  map[string][]fs.DirEntry
+}
+
+// hasFileParent reports whether any parent directory of name is already a
+// file.
+func (afs *archiveFS) hasFileParent(name string) bool {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := afs.files[dir]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addEntry adds an entry for name to its parent directory, creating the
+// parent directories as needed.
+func (afs *archiveFS) addEntry(name string, fi fileInfo) {
+	dir := path.Dir(name)
+	_, exists := afs.dirs[dir]
+	afs.dirs[dir] = append(afs.dirs[dir], fs.FileInfoToDirEntry(fi))
+	if !exists && dir != "." {
+		afs.addEntry(dir, fileInfo{name: path.Base(dir), mode: fs.ModeDir | 0o555})
+	}
+}
+
+// Open implements the [fs.FS] interface.
+func (afs *archiveFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := afs.files[name]; ok {
+		return &openFile{
+			Reader: bytes.NewReader(f.Data),
+			info:   fileInfo{name: path.Base(name), size: int64(len(f.Data)), mode: f.Mode.Perm()},
+		}, nil
+	}
+	if entries, ok := afs.dirs[name]; ok {
+		return &openDir{
+			info:    fileInfo{name: path.Base(name), mode: fs.ModeDir | 0o555},
+			entries: entries,
+		}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile implements the [fs.ReadFileFS] interface.
+func (afs *archiveFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := afs.files[name]
+	if !ok {
+		if _, ok := afs.dirs[name]; ok {
+			return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+		}
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(f.Data), nil
+}
+
+// ReadDir implements the [fs.ReadDirFS] interface.
+func (afs *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, ok := afs.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(entries), nil
+}
+
+// Stat implements the [fs.StatFS] interface.
+func (afs *archiveFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := afs.Open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err.(*fs.PathError).Err}
+	}
+	return f.Stat()
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (fi fileInfo) Name() string { return fi.name }
+func (fi fileInfo) Size() int64  { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.mode == 0 {
+		return 0o444
+	}
+	return fi.mode
+}
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
+
+type openFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openFile) Close() error               { return nil }
+
+type openDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements the [fs.ReadDirFile] interface.
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return slices.Clone(rest[:n]), nil
+}
@@ -0,0 +1,155 @@
+// © 2024 Ilya Mateyko. All rights reserved.
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE.md file.
+
+package txtar
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+)
+
+// A Writer writes an archive to an underlying io.Writer file by file, without
+// keeping the whole archive in memory.
+type Writer struct {
+	w            io.Writer
+	wroteFile    bool
+	wroteComment bool
+}
+
+// NewWriter returns a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteComment writes the archive comment. It must be called at most once,
+// before any call to AddFile.
+func (w *Writer) WriteComment(comment []byte) error {
+	if w.wroteFile || w.wroteComment {
+		return errors.New("txtar: comment must be written once, before files")
+	}
+	w.wroteComment = true
+	_, err := w.w.Write(fixNL(comment))
+	return err
+}
+
+// AddFile writes a file named name with contents read from r. A final newline
+// is added to the contents if it's missing.
+func (w *Writer) AddFile(name string, r io.Reader) error {
+	return w.AddFileMode(name, 0, r)
+}
+
+// AddFileMode is like AddFile, but also records the file mode.
+func (w *Writer) AddFileMode(name string, mode fs.FileMode, r io.Reader) error {
+	w.wroteFile = true
+	if _, err := fmt.Fprintf(w.w, "-- %s --\n", File{Name: name, Mode: mode}.markerName()); err != nil {
+		return err
+	}
+	nlw := &newlineWriter{w: w.w}
+	if _, err := io.Copy(nlw, r); err != nil {
+		return err
+	}
+	if nlw.n > 0 && nlw.last != '\n' {
+		_, err := w.w.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
+
+// newlineWriter tracks the last byte written to w.
+type newlineWriter struct {
+	w    io.Writer
+	n    int64
+	last byte
+}
+
+func (nw *newlineWriter) Write(p []byte) (int, error) {
+	n, err := nw.w.Write(p)
+	if n > 0 {
+		nw.n += int64(n)
+		nw.last = p[n-1]
+	}
+	return n, err
+}
+
+// A Reader reads an archive from an underlying io.Reader file by file, without
+// keeping the whole archive in memory.
+type Reader struct {
+	br      *bufio.Reader
+	comment []byte
+	next    string // name of the next file, if marker has been read
+	err     error
+}
+
+// NewReader returns a new Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// Comment reads the archive comment, if it wasn't read yet, and returns it.
+func (r *Reader) Comment() ([]byte, error) {
+	if r.comment == nil && r.err == nil {
+		r.comment, r.next, r.err = r.readData()
+		if r.comment == nil {
+			r.comment = []byte{}
+		}
+	}
+	return r.comment, ignoreEOF(r.err)
+}
+
+// Files returns an iterator over files of the archive, reading them one at a
+// time. Iteration stops after the first error, which is yielded with an empty
+// File.
+func (r *Reader) Files() iter.Seq2[File, error] {
+	return func(yield func(File, error) bool) {
+		if _, err := r.Comment(); err != nil {
+			yield(File{}, err)
+			return
+		}
+		for r.next != "" {
+			var f File
+			f.Name, f.Mode = parseMarkerName(r.next)
+			f.Data, r.next, r.err = r.readData()
+			if err := ignoreEOF(r.err); err != nil {
+				yield(File{}, err)
+				return
+			}
+			if !yield(f, nil) {
+				return
+			}
+		}
+	}
+}
+
+// readData reads lines up to the next file marker or the end of input,
+// returning the data read and the name from the marker, if any.
+func (r *Reader) readData() (data []byte, name string, err error) {
+	if r.err != nil {
+		return nil, "", r.err
+	}
+	var buf bytes.Buffer
+	for {
+		line, err := r.br.ReadBytes('\n')
+		if len(line) > 0 {
+			if name, _ := isMarker(line); name != "" {
+				return buf.Bytes(), name, err
+			}
+			buf.Write(line)
+		}
+		if err != nil {
+			return fixNL(buf.Bytes()), "", err
+		}
+	}
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
@@ -11,8 +11,8 @@
 //   - diff nicely in git history and code reviews.
 //
 // Non-goals include being a completely general archive format,
-// storing binary data, storing file modes, storing special files like
-// symbolic links, and so on.
+// storing binary data, storing special files like symbolic links,
+// and so on.
 //
 // # Txtar format
 //
@@ -25,6 +25,10 @@
 // file name can be surrounding by additional white space,
 // all of which is stripped.
 //
+// A file marker line may annotate the file's permission bits in octal
+// after the file name, as in "-- script.sh [mode 0755] --".
+// Files without the annotation use the default permissions.
+//
 // If the txtar file is missing a trailing newline on the final line,
 // parsers should consider a final newline to be present anyway.
 //
@@ -37,6 +41,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -48,8 +54,81 @@ type Archive struct {
 
 // A File is a single file in an archive.
 type File struct {
-	Name string // name of file ("foo/bar.txt")
-	Data []byte // text content of file
+	Name string      // name of file ("foo/bar.txt")
+	Data []byte      // text content of file
+	Mode fs.FileMode // permission bits of file (0o755), or zero for the default
+}
+
+// defaultMode is the permission bits of extracted files that have no mode.
+const defaultMode = 0o644
+
+// markerName returns the name of f as written in the file marker line,
+// including the mode annotation, if any.
+func (f File) markerName() string {
+	if f.Mode.Perm() == 0 {
+		return f.Name
+	}
+	return fmt.Sprintf("%s [mode %04o]", f.Name, f.Mode.Perm())
+}
+
+// parseMarkerName splits the name from a file marker line into the file name
+// and mode.
+func parseMarkerName(s string) (name string, mode fs.FileMode) {
+	before, after, ok := strings.Cut(s, " [mode ")
+	if !ok || !strings.HasSuffix(after, "]") {
+		return s, 0
+	}
+	perm, err := strconv.ParseUint(strings.TrimSuffix(after, "]"), 8, 32)
+	if err != nil || perm == 0 || perm > 0o777 {
+		return s, 0
+	}
+	return strings.TrimSpace(before), fs.FileMode(perm)
+}
+
+// Get returns the data of the first file named name.
+func (a *Archive) Get(name string) ([]byte, bool) {
+	for _, f := range a.Files {
+		if f.Name == name {
+			return f.Data, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the data of the first file named name, or appends a new file
+// if there is no such file.
+func (a *Archive) Set(name string, data []byte) {
+	for i := range a.Files {
+		if a.Files[i].Name == name {
+			a.Files[i].Data = data
+			return
+		}
+	}
+	a.Files = append(a.Files, File{Name: name, Data: data})
+}
+
+// Remove removes all files named name.
+func (a *Archive) Remove(name string) {
+	a.Files = slices.DeleteFunc(a.Files, func(f File) bool { return f.Name == name })
+}
+
+// Merge sets each file of src in dst, replacing files with the same names,
+// including their modes. The comment of dst is left intact.
+func Merge(dst, src *Archive) {
+	for _, f := range src.Files {
+		dst.setFile(f)
+	}
+}
+
+// setFile is like Set, but replaces the whole file.
+func (a *Archive) setFile(f File) {
+	for i := range a.Files {
+		if a.Files[i].Name == f.Name {
+			a.Files[i] = f
+			return
+		}
+	}
+	a.Files = append(a.Files, f)
 }
 
 // Format returns the serialized form of an Archive.
@@ -60,7 +139,7 @@ func Format(a *Archive) []byte {
 	var buf bytes.Buffer
 	buf.Write(fixNL(a.Comment))
 	for _, f := range a.Files {
-		fmt.Fprintf(&buf, "-- %s --\n", f.Name)
+		fmt.Fprintf(&buf, "-- %s --\n", f.markerName())
 		buf.Write(fixNL(f.Data))
 	}
 	return buf.Bytes()
@@ -82,7 +161,8 @@ func Parse(data []byte) *Archive {
 	var name string
 	a.Comment, name, data = findFileMarker(data)
 	for name != "" {
-		f := File{name, nil}
+		var f File
+		f.Name, f.Mode = parseMarkerName(name)
 		f.Data, name, data = findFileMarker(data)
 		a.Files = append(a.Files, f)
 	}
@@ -141,20 +221,75 @@ func fixNL(data []byte) []byte {
 	return d
 }
 
-// Extract extracts an archive to dir.
+// ParseStrict is like Parse, but returns an error if the archive contains
+// several files with the same name.
+func ParseStrict(data []byte) (*Archive, error) {
+	a := Parse(data)
+	seen := make(map[string]bool, len(a.Files))
+	for _, f := range a.Files {
+		if seen[f.Name] {
+			return nil, fmt.Errorf("txtar: duplicate file name %q", f.Name)
+		}
+		seen[f.Name] = true
+	}
+	return a, nil
+}
+
+// checkName returns an error if the file name is unsafe to extract: it is
+// absolute, contains ".." elements or otherwise escapes the destination
+// directory.
+func checkName(name string) error {
+	for _, elem := range strings.Split(filepath.ToSlash(name), "/") {
+		if elem == ".." {
+			return fmt.Errorf("txtar: unsafe file name %q: contains \"..\"", name)
+		}
+	}
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("txtar: unsafe file name %q: escapes destination directory", name)
+	}
+	return nil
+}
+
+// Extract extracts an archive to dir. Files are created with their modes, or
+// 0o644 if they don't have one.
+//
+// It returns an error without writing anything if any file name is absolute,
+// contains ".." elements or otherwise escapes dir.
 func Extract(a *Archive, dir string) error {
+	for _, f := range a.Files {
+		if err := checkName(f.Name); err != nil {
+			return err
+		}
+	}
 	for _, f := range a.Files {
 		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(f.Name)), 0o755); err != nil {
 			return err
 		}
-		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o644); err != nil {
+		path := filepath.Join(dir, f.Name)
+		perm := fs.FileMode(defaultMode)
+		if f.Mode.Perm() != 0 {
+			perm = f.Mode.Perm()
+		}
+		// Create the file with its mode, so private files are never readable
+		// by others, even briefly.
+		if err := os.WriteFile(path, f.Data, perm); err != nil {
 			return err
 		}
+		// Set the mode explicitly, since WriteFile is subject to umask and
+		// doesn't change mode of existing files.
+		if f.Mode.Perm() != 0 {
+			if err := os.Chmod(path, f.Mode.Perm()); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
-// FromDir constructs an archive from contents of dir.
+// FromDir constructs an archive from contents of dir. Modes of executable
+// files and of files with permissions narrower than 0o644, such as 0o600, are
+// preserved. Other files, such as 0o664 ones created with umask 002, get no
+// mode, so the archive doesn't depend on the umask it was created with.
 func FromDir(dir string) (*Archive, error) {
 	a := new(Archive)
 
@@ -171,10 +306,19 @@ func FromDir(dir string) (*Archive, error) {
 		if err != nil {
 			return err
 		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var mode fs.FileMode
+		if perm := fi.Mode().Perm(); perm&0o111 != 0 || perm&defaultMode != defaultMode {
+			mode = perm
+		}
 
 		a.Files = append(a.Files, File{
 			Name: d.Name(),
 			Data: b,
+			Mode: mode,
 		})
 
 		return nil
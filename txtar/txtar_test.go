@@ -2,9 +2,14 @@ package txtar
 
 import (
 	"bytes"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestParse(t *testing.T) {
@@ -247,3 +252,299 @@ func createFile(t *testing.T, path, content string) {
 		t.Fatalf("Failed to create file: %v", err)
 	}
 }
+
+func TestStream(t *testing.T) {
+	a := &Archive{
+		Comment: []byte("# comment\n"),
+		Files: []File{
+			{Name: "foo.txt", Data: []byte("content1\n")},
+			{Name: "empty.txt", Data: []byte{}},
+			{Name: "dir/bar.go", Data: []byte("no trailing newline")},
+			{Name: "baz.txt", Data: []byte("line 1\n-- not a marker\nline 3\n")},
+		},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteComment(a.Comment); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range a.Files {
+		if err := w.AddFile(f.Name, bytes.NewReader(f.Data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteComment(a.Comment); err == nil {
+		t.Fatal("WriteComment after AddFile must fail")
+	}
+	if !bytes.Equal(buf.Bytes(), Format(a)) {
+		t.Fatalf("Writer output = %q, Format = %q", buf.Bytes(), Format(a))
+	}
+
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	got := new(Archive)
+	for f, err := range r.Files() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got.Files = append(got.Files, f)
+	}
+	comment, err := r.Comment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Comment = comment
+	if want := Parse(buf.Bytes()); !equal(got, want) {
+		t.Fatalf("Reader = %v, Parse = %v", got, want)
+	}
+}
+
+func TestReaderStopsEarly(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte("-- a --\n1\n-- b --\n2\n-- c --\n3\n")))
+	var names []string
+	for f, err := range r.Files() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		if f.Name == "b" {
+			break
+		}
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("got files %v, want [a b]", names)
+	}
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	for _, name := range []string{
+		"../etc/passwd",
+		"subdir/../../escape.txt",
+		"/etc/passwd",
+		"a/../b.txt",
+		"",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			a := &Archive{Files: []File{
+				{Name: "safe.txt", Data: []byte("safe\n")},
+				{Name: name, Data: []byte("root:x:0:0\n")},
+			}}
+			if err := Extract(a, filepath.Join(dir, "dest")); err == nil {
+				t.Fatalf("Extract must reject %q", name)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "dest", "safe.txt")); !os.IsNotExist(err) {
+				t.Fatalf("Extract must not write anything when rejecting, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	if _, err := ParseStrict([]byte("-- a.txt --\n1\n-- b.txt --\n2\n")); err != nil {
+		t.Fatalf("ParseStrict: %v", err)
+	}
+	if _, err := ParseStrict([]byte("-- a.txt --\n1\n-- a.txt --\n2\n")); err == nil {
+		t.Fatal("ParseStrict must reject duplicate file names")
+	}
+}
+
+func TestArchiveSetGetRemove(t *testing.T) {
+	a := Parse([]byte("# comment\n-- a.txt --\n1\n-- b.txt --\n2\n"))
+
+	a.Set("a.txt", []byte("updated\n"))
+	a.Set("c.txt", []byte("3\n"))
+	want := "# comment\n-- a.txt --\nupdated\n-- b.txt --\n2\n-- c.txt --\n3\n"
+	if got := string(Format(a)); got != want {
+		t.Fatalf("after Set: got %q, want %q", got, want)
+	}
+
+	if data, ok := a.Get("b.txt"); !ok || string(data) != "2\n" {
+		t.Fatalf("Get(b.txt) = %q, %v", data, ok)
+	}
+	if data, ok := a.Get("missing.txt"); ok || data != nil {
+		t.Fatalf("Get(missing.txt) = %q, %v", data, ok)
+	}
+
+	a.Remove("b.txt")
+	a.Remove("missing.txt")
+	want = "# comment\n-- a.txt --\nupdated\n-- c.txt --\n3\n"
+	if got := string(Format(a)); got != want {
+		t.Fatalf("after Remove: got %q, want %q", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dst := Parse([]byte("# dst\n-- a.txt --\n1\n-- b.txt --\n2\n"))
+	src := Parse([]byte("# src\n-- b.txt --\noverwritten\n-- c.txt --\n3\n"))
+	Merge(dst, src)
+	want := "# dst\n-- a.txt --\n1\n-- b.txt --\noverwritten\n-- c.txt --\n3\n"
+	if got := string(Format(dst)); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMergeModes(t *testing.T) {
+	dst := Parse([]byte("-- a.sh [mode 0755] --\nold\n-- b.txt --\n2\n"))
+	src := Parse([]byte("-- a.txt --\n1\n-- b.txt [mode 0600] --\nsecret\n-- c.sh [mode 0755] --\n3\n"))
+	Merge(dst, src)
+	want := "-- a.sh [mode 0755] --\nold\n-- b.txt [mode 0600] --\nsecret\n-- a.txt --\n1\n-- c.sh [mode 0755] --\n3\n"
+	if got := string(Format(dst)); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFS(t *testing.T) {
+	a := Parse([]byte(`-- hello.txt --
+hello
+-- subdir/file.txt --
+nested
+-- subdir/deeper/more.txt --
+deeper
+-- hello.txt --
+duplicate
+`))
+	fsys := a.FS()
+
+	if err := fstest.TestFS(fsys, "hello.txt", "subdir/file.txt", "subdir/deeper/more.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fs.ReadFile(fsys, "subdir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "nested\n" {
+		t.Errorf("subdir/file.txt: got %q", b)
+	}
+	b, err = fs.ReadFile(fsys, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello\n" {
+		t.Errorf("hello.txt: got %q, want the first file with this name", b)
+	}
+
+	var walked []string
+	if err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "hello.txt", "subdir", "subdir/deeper", "subdir/deeper/more.txt", "subdir/file.txt"}
+	if !slices.Equal(walked, want) {
+		t.Errorf("WalkDir: got %v, want %v", walked, want)
+	}
+
+	if _, err := fs.Stat(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(missing.txt): want fs.ErrNotExist, got %v", err)
+	}
+
+	// A name used both as a file and as a directory: the first one wins.
+	conflicts := map[string]struct {
+		archive  string
+		wantFile string
+		wantDir  string
+	}{
+		"file first": {
+			archive:  "-- a --\nfile\n-- a/b --\nnested\n-- c --\n",
+			wantFile: "a",
+		},
+		"dir first": {
+			archive: "-- a/b --\nnested\n-- a --\nfile\n-- c --\n",
+			wantDir: "a",
+		},
+	}
+	for name, tc := range conflicts {
+		t.Run(name, func(t *testing.T) {
+			fsys := Parse([]byte(tc.archive)).FS()
+			var expected []string
+			if tc.wantFile != "" {
+				expected = []string{tc.wantFile, "c"}
+			} else {
+				expected = []string{tc.wantDir + "/b", "c"}
+			}
+			if err := fstest.TestFS(fsys, expected...); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := fs.ReadDir(fsys, ".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			if want := []string{"a", "c"}; !slices.Equal(names, want) {
+				t.Errorf("root entries: got %v, want %v", names, want)
+			}
+		})
+	}
+}
+
+func TestModes(t *testing.T) {
+	src := t.TempDir()
+	createFile(t, filepath.Join(src, "script.sh"), "#!/bin/sh\necho hello\n")
+	if err := os.Chmod(filepath.Join(src, "script.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	createFile(t, filepath.Join(src, "plain.txt"), "plain\n")
+	if err := os.Chmod(filepath.Join(src, "plain.txt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	createFile(t, filepath.Join(src, "private.txt"), "private\n")
+	if err := os.Chmod(filepath.Join(src, "private.txt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Group-writable files, as created with umask 002, get no mode either.
+	createFile(t, filepath.Join(src, "shared.txt"), "shared\n")
+	if err := os.Chmod(filepath.Join(src, "shared.txt"), 0o664); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := FromDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted := string(Format(a))
+	for _, want := range []string{"-- script.sh [mode 0755] --\n", "-- private.txt [mode 0600] --\n", "-- plain.txt --\n", "-- shared.txt --\n"} {
+		if !strings.Contains(formatted, want) {
+			t.Fatalf("formatted archive doesn't contain %q:\n%s", want, formatted)
+		}
+	}
+
+	dst := t.TempDir()
+	if err := Extract(Parse([]byte(formatted)), dst); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]fs.FileMode{"script.sh": 0o755, "private.txt": 0o600, "plain.txt": 0o644} {
+		fi, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != want {
+			t.Errorf("%s: got mode %o, want %o", name, got, want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	a := Parse([]byte("-- a.sh [mode 0700] --\n-- b [mode bogus] --\n-- c.txt --\n"))
+	want := []File{
+		{Name: "a.sh", Data: []byte{}, Mode: 0o700},
+		{Name: "b [mode bogus]", Data: []byte{}},
+		{Name: "c.txt", Data: []byte{}},
+	}
+	if len(a.Files) != len(want) {
+		t.Fatalf("got %d files, want %d", len(a.Files), len(want))
+	}
+	for i, f := range a.Files {
+		if f.Name != want[i].Name || f.Mode != want[i].Mode {
+			t.Errorf("file %d: got %q (mode %o), want %q (mode %o)", i, f.Name, f.Mode, want[i].Name, want[i].Mode)
+		}
+	}
+}
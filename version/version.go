@@ -8,6 +8,7 @@ package version
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
 	"strings"
 	"sync"
@@ -78,7 +79,15 @@ var (
 func loadInfo(buildinfo func() (*debug.BuildInfo, bool)) Info {
 	bi, ok := buildinfo()
 	if !ok {
-		panic("build info is absent from binary; make sure you build it with module support")
+		// Build info is absent, e.g. when the binary was built without module
+		// support. Report what we know from the runtime.
+		return Info{
+			Name:    filepath.Base(os.Args[0]),
+			Version: "unknown",
+			Go:      runtime.Version(),
+			OS:      runtime.GOOS,
+			Arch:    runtime.GOARCH,
+		}
 	}
 
 	i := &Info{Go: bi.GoVersion}
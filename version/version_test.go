@@ -7,6 +7,8 @@ package version
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"runtime"
 	"runtime/debug"
 	"testing"
 
@@ -59,3 +61,44 @@ func TestInfoString(t *testing.T) {
 		return []byte(i.String())
 	}, *update)
 }
+
+func TestLoadInfoFields(t *testing.T) {
+	t.Parallel()
+
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.25.0",
+		Path:      "example.com/cmd/tool",
+		Main:      debug.Module{Path: "example.com", Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "GOOS", Value: "linux"},
+			{Key: "GOARCH", Value: "amd64"},
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2024-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	got := loadInfo(func() (*debug.BuildInfo, bool) { return bi, true })
+	testutil.AssertEqual(t, got, Info{
+		Name:    "tool",
+		Version: "git",
+		Commit:  "01234567",
+		BuiltAt: "2024-01-02T03:04:05Z",
+		Dirty:   true,
+		Go:      "go1.25.0",
+		OS:      "linux",
+		Arch:    "amd64",
+	})
+}
+
+func TestLoadInfoAbsent(t *testing.T) {
+	t.Parallel()
+
+	got := loadInfo(func() (*debug.BuildInfo, bool) { return nil, false })
+	testutil.AssertEqual(t, got, Info{
+		Name:    filepath.Base(os.Args[0]),
+		Version: "unknown",
+		Go:      runtime.Version(),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+	})
+}